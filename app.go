@@ -8,7 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -31,13 +33,31 @@ type App struct {
 	syncEnabled  bool
 	syncInterval time.Duration
 	syncRunning  bool
-	syncStopCh   chan bool
+	// 同步控制
+	syncMu        sync.Mutex
+	serviceCtx    context.Context    // 同步服务的上下文，服务停止时取消
+	serviceCancel context.CancelFunc // 停止同步服务
+	runCancel     context.CancelFunc // 取消正在执行的同步
+	syncPause     syncPauser
+	// 文件监控
+	watcher            *syncWatcher
+	remoteWatcher      *remoteWatcher
+	disableFileWatcher bool
 	// 群晖Drive风格功能
 	syncRules                 []SyncRule
 	fileVersions              map[string][]FileVersion
+	fileVersionsMu            sync.Mutex
 	conflictFiles             []ConflictFile
 	shareLinks                map[string]ShareLink
-	syncMode                  string // "full", "selective", "backup", "incremental"
+	shareMu                   sync.Mutex
+	shareAttempts             map[string]shareAttempt // 分享链接的密码尝试记录
+	shareConfig               ShareConfig
+	shareGateway              *shareGateway
+	trashConfig               TrashConfig
+	ruleCiphers               map[string]*ruleCipher // 已解锁的加密规则
+	cipherMu                  sync.Mutex
+	secretMu                  sync.Mutex // 保护本机密钥存储文件
+	syncMode                  string     // "full", "selective", "backup", "incremental"
 	lastSyncTime              time.Time
 	configDir                 string
 	jsonParser                *JSONParser
@@ -45,6 +65,19 @@ type App struct {
 	config                    Config
 	// 客户端特性
 	clientFeatures *ClientFeatures // 客户端特性管理器
+	// 传输调度
+	syncMaxConcurrency  int           // 全局最大并发传输数，0 表示自动
+	transferConcurrency int           // 性能优化计算出的默认并发数
+	transferSlots       chan struct{} // 全局传输并发槽位，所有规则共享
+	transferMu          sync.Mutex
+	// 带宽限制
+	bandwidthConfig BandwidthConfig
+	bandwidthMu     sync.Mutex
+	uploadLimiter   *bandwidthLimiter
+	downloadLimiter *bandwidthLimiter
+	// 完整性校验
+	verifyTransfers bool          // 每次传输后校验内容
+	scrubInterval   time.Duration // 定期完整性检查间隔，0 表示不检查
 }
 
 // JSONParser 是一个JSON解析器包装器
@@ -104,12 +137,14 @@ func NewApp() *App {
 		syncEnabled:  false,
 		syncInterval: 5 * time.Minute,
 		syncRunning:  false,
-		syncStopCh:   make(chan bool),
 		// 群晖Drive风格功能初始化
 		syncRules:                 []SyncRule{},
 		fileVersions:              make(map[string][]FileVersion),
 		conflictFiles:             []ConflictFile{},
 		shareLinks:                make(map[string]ShareLink),
+		shareAttempts:             make(map[string]shareAttempt),
+		trashConfig:               TrashConfig{RetentionDays: defaultTrashRetentionDays},
+		ruleCiphers:               make(map[string]*ruleCipher),
 		syncMode:                  "full",
 		lastSyncTime:              time.Now().Add(-24 * time.Hour),
 		defaultConflictResolution: "ask", // 默认冲突解决方式：询问用户
@@ -119,6 +154,10 @@ func NewApp() *App {
 	// 初始化客户端特性
 	app.clientFeatures = NewClientFeatures(app)
 
+	// 初始化带宽限制
+	app.uploadLimiter = newBandwidthLimiter(app.currentUploadRate)
+	app.downloadLimiter = newBandwidthLimiter(app.currentDownloadRate)
+
 	// 加载用户数据
 	app.loadUsers()
 
@@ -158,6 +197,14 @@ func (a *App) startup(ctx context.Context) {
 		fmt.Printf("初始化同步功能失败: %v\n", err)
 	}
 
+	// 加载分享链接并启动分享网关
+	if err := a.initShareLinks(); err != nil {
+		fmt.Printf("初始化分享链接失败: %v\n", err)
+	}
+
+	// 定期清除回收站中的过期项目
+	go a.trashPurgeService(ctx)
+
 	fmt.Println("应用启动完成")
 }
 
@@ -219,9 +266,12 @@ func (a *App) beforeClose(ctx context.Context) (prevent bool) {
 	// 清理客户端特性资源
 	a.clientFeatures.Cleanup()
 
+	// 停止分享网关
+	a.stopShareGateway()
+
 	// 停止同步服务
-	if a.syncRunning {
-		a.syncStopCh <- true
+	if a.syncRunning || a.isSyncActive() {
+		a.cancelSync()
 		// 等待同步服务停止
 		time.Sleep(500 * time.Millisecond)
 	}
@@ -363,11 +413,18 @@ func (a *App) GetSystemInfo() map[string]interface{} {
 // Config 应用配置结构体
 type Config struct {
 	Minio      MinioConfig `json:"minio"`
+	Share      ShareConfig `json:"share"`
+	Trash      TrashConfig `json:"trash"`
 	SyncConfig struct {
-		Enabled                   bool   `json:"enabled"`
-		Interval                  int    `json:"interval"` // 秒
-		Mode                      string `json:"mode"`
-		DefaultConflictResolution string `json:"defaultConflictResolution"`
+		Enabled                   bool            `json:"enabled"`
+		Interval                  int             `json:"interval"` // 秒
+		Mode                      string          `json:"mode"`
+		DefaultConflictResolution string          `json:"defaultConflictResolution"`
+		MaxConcurrency            int             `json:"maxConcurrency"`     // 全局最大并发传输数，0 表示自动
+		Bandwidth                 BandwidthConfig `json:"bandwidth"`          // 带宽限制
+		DisableFileWatcher        bool            `json:"disableFileWatcher"` // 禁用实时文件监控，只依靠定时扫描
+		VerifyTransfers           bool            `json:"verifyTransfers"`    // 每次传输后校验内容
+		ScrubInterval             int             `json:"scrubInterval"`      // 定期完整性检查间隔（小时），0 表示不检查
 	} `json:"sync"`
 	ISCSIConfig struct {
 		Enabled         bool                 `json:"enabled"`
@@ -403,10 +460,21 @@ func (a *App) loadConfig() {
 
 	// 更新配置
 	a.minioConfig = config.Minio
+	a.shareConfig = config.Share
+	a.trashConfig = config.Trash
 	a.syncEnabled = config.SyncConfig.Enabled
 	a.syncInterval = time.Duration(config.SyncConfig.Interval) * time.Second
 	a.syncMode = config.SyncConfig.Mode
 	a.defaultConflictResolution = config.SyncConfig.DefaultConflictResolution
+	a.syncMaxConcurrency = config.SyncConfig.MaxConcurrency
+	a.disableFileWatcher = config.SyncConfig.DisableFileWatcher
+	a.verifyTransfers = config.SyncConfig.VerifyTransfers
+	if config.SyncConfig.ScrubInterval > 0 {
+		a.scrubInterval = time.Duration(config.SyncConfig.ScrubInterval) * time.Hour
+	}
+	if err := validateBandwidthConfig(config.SyncConfig.Bandwidth); err == nil {
+		a.bandwidthConfig = config.SyncConfig.Bandwidth
+	}
 
 	// 如果同步间隔太短，设置为默认值
 	if a.syncInterval < time.Minute {
@@ -429,6 +497,8 @@ func (a *App) saveConfig() error {
 	// 创建配置对象
 	config := Config{
 		Minio: a.minioConfig,
+		Share: a.shareConfig,
+		Trash: a.trashConfig,
 	}
 
 	// 同步配置
@@ -436,6 +506,11 @@ func (a *App) saveConfig() error {
 	config.SyncConfig.Interval = int(a.syncInterval.Seconds())
 	config.SyncConfig.Mode = a.syncMode
 	config.SyncConfig.DefaultConflictResolution = a.defaultConflictResolution
+	config.SyncConfig.MaxConcurrency = a.syncMaxConcurrency
+	config.SyncConfig.DisableFileWatcher = a.disableFileWatcher
+	config.SyncConfig.VerifyTransfers = a.verifyTransfers
+	config.SyncConfig.ScrubInterval = int(a.scrubInterval.Hours())
+	config.SyncConfig.Bandwidth = a.GetBandwidthConfig()
 
 	// 更新配置对象
 	a.config = config
@@ -493,7 +568,7 @@ func (a *App) GetMinioFileInfo(path string) (MinioFileInfo, error) {
 	}
 
 	// 获取对象信息
-	info, err := a.minioClient.StatObject(context.Background(), a.minioConfig.BucketName, path, minio.StatObjectOptions{})
+	info, err := a.statObject(context.Background(), path, "")
 	if err != nil {
 		return MinioFileInfo{}, err
 	}
@@ -505,37 +580,129 @@ func (a *App) GetMinioFileInfo(path string) (MinioFileInfo, error) {
 		Size:         info.Size,
 		LastModified: info.LastModified,
 		IsDir:        false,
+		ETag:         info.ETag,
+		VersionID:    info.VersionID,
+		SHA256:       objectChecksum(info.UserMetadata),
 	}, nil
 }
 
 // UploadFileToMinio 上传文件到MinIO
 func (a *App) UploadFileToMinio(localPath, remotePath string) error {
+	_, _, _, err := a.uploadFile(context.Background(), localPath, remotePath)
+	return err
+}
+
+// uploadFile 上传文件到MinIO，并返回上传结果（ETag、版本ID等）
+// 同时返回上传前获取的文件信息和内容MD5，上传期间文件被修改时，同步索引记录的仍是上传前的状态，下次同步会重新上传
+func (a *App) uploadFile(ctx context.Context, localPath, remotePath string) (minio.UploadInfo, os.FileInfo, string, error) {
 	if a.minioClient == nil {
-		return fmt.Errorf("MinIO客户端未初始化")
+		return minio.UploadInfo{}, nil, "", fmt.Errorf("MinIO客户端未初始化")
 	}
 
 	// 打开文件
 	file, err := os.Open(localPath)
 	if err != nil {
-		return fmt.Errorf("打开文件失败: %v", err)
+		return minio.UploadInfo{}, nil, "", fmt.Errorf("打开文件失败: %v", err)
 	}
 	defer file.Close()
 
 	// 获取文件信息
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("获取文件信息失败: %v", err)
+		return minio.UploadInfo{}, nil, "", fmt.Errorf("获取文件信息失败: %v", err)
 	}
 
-	// 上传文件
-	_, err = a.minioClient.PutObject(context.Background(), a.minioConfig.BucketName, remotePath, file, fileInfo.Size(), minio.PutObjectOptions{
-		ContentType: "application/octet-stream",
-	})
+	// 内容校验和及文件属性写入对象元数据，比较时无需下载对象，下载时可还原属性
+	contentMD5, checksum, err := calculateChecksums(localPath)
 	if err != nil {
-		return fmt.Errorf("上传文件失败: %v", err)
+		return minio.UploadInfo{}, nil, "", err
 	}
 
-	return nil
+	enc, err := a.cipherForKey(remotePath)
+	if err != nil {
+		return minio.UploadInfo{}, nil, "", err
+	}
+
+	// 启用分块存储的规则，大文件只上传变化的分块
+	if rule, ok := a.chunkingRuleFor(remotePath, fileInfo.Size()); ok {
+		info, manifestSize, err := a.uploadChunked(ctx, rule, enc, file, fileInfo, localPath, remotePath, checksum)
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+		if a.verifyTransfers {
+			if err := a.verifyUploaded(ctx, localPath, remotePath, manifestSize, checksum); err != nil {
+				return minio.UploadInfo{}, nil, "", err
+			}
+		}
+		return info, fileInfo, contentMD5, nil
+	}
+
+	// 启用压缩的规则先压缩再上传（加密规则压缩后再加密），压缩后没有变小时上传原文件
+	var body io.Reader = file
+	size := fileInfo.Size()
+	metadata := fileMetadata(fileInfo, checksum, a.preserveOwner(remotePath))
+	algorithm, err := a.compressionFor(remotePath, localPath)
+	if err != nil {
+		return minio.UploadInfo{}, nil, "", err
+	}
+	if algorithm != "" {
+		compressed, compressedSize, err := compressToTemp(file, algorithm)
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+		defer os.Remove(compressed.Name())
+		defer compressed.Close()
+
+		if compressedSize < size {
+			body = compressed
+			size = compressedSize
+			metadata[compressionMetadataKey] = algorithm
+			metadata[plainSizeMetadataKey] = strconv.FormatInt(fileInfo.Size(), 10)
+		} else if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return minio.UploadInfo{}, nil, "", fmt.Errorf("读取文件失败: %v", err)
+		}
+	}
+	transformed := enc != nil || metadata[compressionMetadataKey] != ""
+
+	var info minio.UploadInfo
+	if enc != nil {
+		// 加密规则在客户端加密后上传
+		info, err = a.putEncrypted(ctx, enc, remotePath, body, size, metadata, checksum, a.newTransferProgress(remotePath, enc.encryptedSize(size)))
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+	} else if !transformed && fileInfo.Size() >= resumableUploadThreshold {
+		// 大文件使用可断点续传的分片上传
+		info, err = a.uploadFileResumable(ctx, file, localPath, remotePath, fileInfo, checksum)
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+	} else {
+		sse, err := a.serverSideFor(remotePath)
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+
+		// 上传文件；压缩后的内容每次重新生成，不使用断点续传
+		info, err = a.minioClient.PutObject(ctx, a.minioConfig.BucketName, remotePath, a.limitUpload(ctx, body), size, minio.PutObjectOptions{
+			ContentType:          "application/octet-stream",
+			UserMetadata:         metadata,
+			Progress:             a.newTransferProgress(remotePath, size),
+			ServerSideEncryption: sse,
+		})
+		if err != nil {
+			return minio.UploadInfo{}, nil, "", fmt.Errorf("上传文件失败: %v", err)
+		}
+	}
+
+	// 校验服务端收到的内容
+	if a.verifyTransfers {
+		if err := a.verifyUploaded(ctx, localPath, remotePath, size, checksum); err != nil {
+			return minio.UploadInfo{}, nil, "", err
+		}
+	}
+
+	return info, fileInfo, contentMD5, nil
 }
 
 // DownloadFileFromMinio 从MinIO下载文件
@@ -545,14 +712,14 @@ func (a *App) DownloadFileFromMinio(remotePath string) ([]byte, error) {
 	}
 
 	// 获取对象
-	obj, err := a.minioClient.GetObject(context.Background(), a.minioConfig.BucketName, remotePath, minio.GetObjectOptions{})
+	content, _, _, err := a.openObject(context.Background(), remotePath, "", "")
 	if err != nil {
 		return nil, fmt.Errorf("获取对象失败: %v", err)
 	}
-	defer obj.Close()
+	defer content.Close()
 
 	// 读取对象内容
-	data, err := io.ReadAll(obj)
+	data, err := io.ReadAll(content)
 	if err != nil {
 		return nil, fmt.Errorf("读取对象内容失败: %v", err)
 	}
@@ -571,8 +738,13 @@ func (a *App) CreateMinioFolder(path string) error {
 		path = path + "/"
 	}
 
+	sse, err := a.serverSideFor(path)
+	if err != nil {
+		return err
+	}
+
 	// 创建空对象作为文件夹
-	_, err := a.minioClient.PutObject(context.Background(), a.minioConfig.BucketName, path, nil, 0, minio.PutObjectOptions{})
+	_, err = a.minioClient.PutObject(context.Background(), a.minioConfig.BucketName, path, nil, 0, minio.PutObjectOptions{ServerSideEncryption: sse})
 	if err != nil {
 		return fmt.Errorf("创建文件夹失败: %v", err)
 	}
@@ -606,8 +778,8 @@ func (a *App) ListMinioFiles(path string) ([]MinioFileInfo, error) {
 			return nil, fmt.Errorf("列出对象失败: %v", object.Err)
 		}
 
-		// 跳过当前目录
-		if object.Key == path {
+		// 跳过当前目录和不属于同步文件的对象
+		if object.Key == path || !isSyncManagedKey(path, object.Key) {
 			continue
 		}
 
@@ -621,6 +793,8 @@ func (a *App) ListMinioFiles(path string) ([]MinioFileInfo, error) {
 			Size:         object.Size,
 			LastModified: object.LastModified,
 			IsDir:        isDir,
+			ETag:         object.ETag,
+			VersionID:    object.VersionID,
 		})
 	}
 
@@ -631,22 +805,30 @@ func (a *App) ListMinioFiles(path string) ([]MinioFileInfo, error) {
 
 // 同步进度监控功能
 type SyncProgress struct {
-	TotalFiles      int     `json:"totalFiles"`
-	ProcessedFiles  int     `json:"processedFiles"`
-	UploadedFiles   int     `json:"uploadedFiles"`
-	DownloadedFiles int     `json:"downloadedFiles"`
-	CurrentFile     string  `json:"currentFile"`
-	Progress        float64 `json:"progress"`
-	Status          string  `json:"status"` // "running", "paused", "completed", "error"
-	Error           string  `json:"error"`
+	TotalFiles       int     `json:"totalFiles"`
+	ProcessedFiles   int     `json:"processedFiles"`
+	UploadedFiles    int     `json:"uploadedFiles"`
+	DownloadedFiles  int     `json:"downloadedFiles"`
+	CurrentFile      string  `json:"currentFile"`
+	CurrentFileBytes int64   `json:"currentFileBytes"` // 当前文件已传输字节数
+	CurrentFileSize  int64   `json:"currentFileSize"`  // 当前文件总字节数
+	TransferredBytes int64   `json:"transferredBytes"` // 本次同步累计传输字节数
+	Progress         float64 `json:"progress"`
+	Status           string  `json:"status"` // "running", "paused", "completed", "cancelled", "error"
+	Error            string  `json:"error"`
 }
 
 // 同步进度监控
-var syncProgress SyncProgress
+var (
+	syncProgress   SyncProgress
+	syncProgressMu sync.Mutex // 并发传输时保护同步进度
+)
 
 // UpdateSyncProgress 更新同步进度
 func (a *App) UpdateSyncProgress(progress SyncProgress) {
+	syncProgressMu.Lock()
 	syncProgress = progress
+	syncProgressMu.Unlock()
 
 	// 发送进度更新到前端
 	wailsRuntime.EventsEmit(a.ctx, "sync-progress-update", progress)
@@ -654,24 +836,32 @@ func (a *App) UpdateSyncProgress(progress SyncProgress) {
 
 // GetSyncProgress 获取同步进度
 func (a *App) GetSyncProgress() SyncProgress {
+	syncProgressMu.Lock()
+	defer syncProgressMu.Unlock()
 	return syncProgress
 }
 
 // ResetSyncProgress 重置同步进度
 func (a *App) ResetSyncProgress() {
+	syncProgressMu.Lock()
 	syncProgress = SyncProgress{
-		TotalFiles:      0,
-		ProcessedFiles:  0,
-		UploadedFiles:   0,
-		DownloadedFiles: 0,
-		CurrentFile:     "",
-		Progress:        0,
-		Status:          "idle",
-		Error:           "",
-	}
+		TotalFiles:       0,
+		ProcessedFiles:   0,
+		UploadedFiles:    0,
+		DownloadedFiles:  0,
+		CurrentFile:      "",
+		CurrentFileBytes: 0,
+		CurrentFileSize:  0,
+		TransferredBytes: 0,
+		Progress:         0,
+		Status:           "idle",
+		Error:            "",
+	}
+	progress := syncProgress
+	syncProgressMu.Unlock()
 
 	// 发送进度更新到前端
-	wailsRuntime.EventsEmit(a.ctx, "sync-progress-update", syncProgress)
+	wailsRuntime.EventsEmit(a.ctx, "sync-progress-update", progress)
 }
 
 // 同步日志记录功能
@@ -727,12 +917,15 @@ func (a *App) ClearSyncLogs() {
 
 // FileVersion 文件版本
 type FileVersion struct {
-	Path      string    `json:"path"`
-	Version   int       `json:"version"`
-	Size      int64     `json:"size"`
-	ModTime   time.Time `json:"modTime"`
-	Checksum  string    `json:"checksum"`
-	CreatedBy string    `json:"createdBy"`
+	Path           string    `json:"path"`
+	Version        int       `json:"version"`
+	VersionID      string    `json:"versionId"`
+	Size           int64     `json:"size"`
+	ModTime        time.Time `json:"modTime"`
+	Checksum       string    `json:"checksum"`
+	CreatedBy      string    `json:"createdBy"`
+	IsLatest       bool      `json:"isLatest"`
+	IsDeleteMarker bool      `json:"isDeleteMarker"` // 删除标记，表示文件在该版本被删除
 }
 
 // ShareLink 分享链接
@@ -800,13 +993,12 @@ func (a *App) HandleSyncError(err error, operation string, file string) {
 // 同步性能优化功能
 func (a *App) OptimizeSyncPerformance() {
 	// 根据系统资源调整同步参数
-	cpuCount := runtime.NumCPU()
+	concurrency := defaultTransferConcurrency()
 
-	// 设置并发数
-	concurrency := cpuCount
-	if concurrency > 4 {
-		concurrency = 4 // 最大并发数限制为4
-	}
+	// 设置并发数，未配置全局并发数时由传输调度器使用
+	a.transferMu.Lock()
+	a.transferConcurrency = concurrency
+	a.transferMu.Unlock()
 
 	// 设置缓冲区大小
 	bufferSize := 1024 * 1024 // 1MB
@@ -906,12 +1098,13 @@ func (a *App) UpdateSyncConfig(enabled bool, interval int, mode string, defaultC
 
 // MinioConfig MinIO配置结构体
 type MinioConfig struct {
-	Endpoint        string `json:"endpoint"`
-	AccessKeyID     string `json:"accessKeyID"`
-	SecretAccessKey string `json:"secretAccessKey"`
-	UseSSL          bool   `json:"useSSL"`
-	BucketName      string `json:"bucketName"`
-	Enabled         bool   `json:"enabled"`
+	Endpoint        string           `json:"endpoint"`
+	AccessKeyID     string           `json:"accessKeyID"`
+	SecretAccessKey string           `json:"secretAccessKey"`
+	UseSSL          bool             `json:"useSSL"`
+	BucketName      string           `json:"bucketName"`
+	Enabled         bool             `json:"enabled"`
+	Encryption      ServerEncryption `json:"encryption"` // 服务端加密
 }
 
 // ISCSIDiscoveredTarget iSCSI发现的目标器
@@ -968,10 +1161,14 @@ func (a *App) Register(username, password string) error {
 		return fmt.Errorf("用户名已存在")
 	}
 
-	// 创建新用户，密码使用哈希加密
+	// 创建新用户，只保存密码的加盐哈希
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
 	a.users[username] = User{
 		Username: username,
-		Password: hashPassword(password),
+		Password: hash,
 	}
 
 	// 保存用户数据
@@ -990,13 +1187,27 @@ func (a *App) Login(username, password string) AuthResponse {
 	}
 
 	// 检查密码是否正确（比较哈希值）
-	if user.Password != hashPassword(password) {
+	ok, outdated := verifyPassword(password, user.Password)
+	if !ok {
 		return AuthResponse{
 			Success: false,
 			Message: "密码错误",
 		}
 	}
 
+	// 旧版本的无盐哈希在登录成功后按当前方式重新计算，失败时不影响本次登录
+	if outdated {
+		if hash, err := hashPassword(password); err != nil {
+			fmt.Printf("更新密码哈希失败: %v\n", err)
+		} else {
+			user.Password = hash
+			a.users[username] = user
+			if err := a.saveUsers(); err != nil {
+				fmt.Printf("更新密码哈希失败: %v\n", err)
+			}
+		}
+	}
+
 	// 设置当前用户
 	a.currentUser = username
 	a.isLoggedIn = true
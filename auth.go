@@ -1,14 +1,76 @@
 package main
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// 密码哈希参数，编码在哈希字符串中，调整后旧哈希仍可验证，并在下次登录时按新参数重新计算
+const (
+	passwordTime    = 3
+	passwordMemory  = 64 * 1024
+	passwordThreads = 4
+	passwordSaltLen = 16
+	passwordKeyLen  = 32
 )
 
-// 哈希密码
-func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+// hashPassword 使用 argon2id 和随机盐计算密码哈希
+// 格式为 $argon2id$v=19$m=65536,t=3,p=4$<盐>$<哈希>，盐和哈希为不带填充的Base64
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成密码盐失败: %v", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, passwordTime, passwordMemory, passwordThreads, passwordKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, passwordMemory, passwordTime, passwordThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword 验证密码是否与保存的哈希一致
+// 第二个返回值表示哈希需要重新计算：旧版本的无盐 SHA-256 哈希，或参数与当前设置不同
+func verifyPassword(password, encoded string) (bool, bool) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		hash := sha256.Sum256([]byte(password))
+		legacy := hex.EncodeToString(hash[:])
+		return subtle.ConstantTimeCompare([]byte(legacy), []byte(encoded)) == 1, true
+	}
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil || time == 0 || threads == 0 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false, false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return false, false
+	}
+	outdated := memory != passwordMemory || time != passwordTime || threads != passwordThreads ||
+		len(salt) != passwordSaltLen || len(expected) != passwordKeyLen
+	return true, outdated
 }
 
 // AuthResponse 认证响应结构
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// RestoreOptions 快照恢复选项
+type RestoreOptions struct {
+	Path   string `json:"path"`   // 只恢复快照中的该子目录或文件，空表示整个快照
+	Target string `json:"target"` // 恢复到的本地目录，空表示规则的本地路径
+	Force  bool   `json:"force"`  // 覆盖比快照更新的本地文件
+}
+
+// RestoreResult 快照恢复结果
+type RestoreResult struct {
+	Snapshot     string   `json:"snapshot"`
+	Target       string   `json:"target"`
+	Restored     int      `json:"restored"`
+	Unchanged    int      `json:"unchanged"`    // 本地内容与快照相同，无需恢复
+	Skipped      []string `json:"skipped"`      // 本地文件比快照新，未覆盖
+	RestoreBytes int64    `json:"restoreBytes"` // 需要恢复的字节数
+	Errors       []string `json:"errors"`
+}
+
+// restoreItem 快照中需要恢复的单个文件
+type restoreItem struct {
+	RelPath string // 快照内的相对路径
+	Key     string // 内容所在的对象键
+	Hash    string // 内容SHA-256，旧格式快照为空
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+}
+
+// RestoreSnapshot 将备份快照整体或其中的子目录恢复到本地目录
+// snapshot 为快照名称（如 backup_20240101_120000）或 latest
+func (a *App) RestoreSnapshot(ruleID, snapshot string, options RestoreOptions) (RestoreResult, error) {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	// 与同步互斥，避免恢复过程中的文件被同步
+	ctx, ok := a.beginSyncRun(context.Background())
+	if !ok {
+		return RestoreResult{}, fmt.Errorf("已有同步正在执行，请稍后再试")
+	}
+	defer a.endSyncRun()
+
+	result, err := a.restoreSnapshot(ctx, rule, snapshot, options)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	return *result, nil
+}
+
+// findBackupSnapshot 按名称查找快照，latest 表示最新的快照
+func (a *App) findBackupSnapshot(ctx context.Context, rule SyncRule, name string) (BackupSnapshot, error) {
+	snapshots, err := a.listBackupSnapshots(ctx, rule)
+	if err != nil {
+		return BackupSnapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return BackupSnapshot{}, fmt.Errorf("规则 '%s' 没有备份快照", rule.Name)
+	}
+	if name == "latest" {
+		return snapshots[0], nil
+	}
+
+	name = strings.TrimSuffix(filepath.ToSlash(name), "/")
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name || snapshot.Path == name {
+			return snapshot, nil
+		}
+	}
+	return BackupSnapshot{}, fmt.Errorf("未找到备份快照: %s", name)
+}
+
+// restoreSnapshot 恢复快照，逐个文件流式下载
+func (a *App) restoreSnapshot(ctx context.Context, rule SyncRule, name string, options RestoreOptions) (*RestoreResult, error) {
+	snapshot, err := a.findBackupSnapshot(ctx, rule, name)
+	if err != nil {
+		return nil, err
+	}
+
+	target := options.Target
+	if target == "" {
+		target = rule.LocalPath
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return nil, fmt.Errorf("无效的恢复目录: %v", err)
+	}
+
+	items, err := a.snapshotRestoreItems(ctx, rule, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只恢复指定的子目录或文件
+	subtree := strings.Trim(filepath.ToSlash(options.Path), "/")
+	result := &RestoreResult{Snapshot: snapshot.Name, Target: target, Skipped: []string{}, Errors: []string{}}
+	var tasks []transferTask
+	for _, item := range items {
+		if subtree != "" && item.RelPath != subtree && !strings.HasPrefix(item.RelPath, subtree+"/") {
+			continue
+		}
+
+		localPath := filepath.Join(target, filepath.FromSlash(item.RelPath))
+		// 拒绝写到恢复目录之外的路径
+		if !strings.HasPrefix(localPath, target+string(filepath.Separator)) {
+			result.Errors = append(result.Errors, fmt.Sprintf("无效的快照路径: %s", item.RelPath))
+			continue
+		}
+
+		restore, err := a.shouldRestore(localPath, item, options.Force)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		switch restore {
+		case restoreUnchanged:
+			result.Unchanged++
+			continue
+		case restoreSkip:
+			result.Skipped = append(result.Skipped, item.RelPath)
+			continue
+		}
+
+		task := transferTask{
+			Kind:       transferDownload,
+			RelPath:    item.RelPath,
+			LocalPath:  localPath,
+			RemotePath: item.Key,
+			RemoteFile: MinioFileInfo{Path: item.Key, Size: item.Size},
+			Size:       item.Size,
+		}
+		// 去重快照的内容对象可能被多个文件共用，修改时间和权限以清单为准
+		if item.Hash != "" {
+			task.ModTime = item.ModTime
+			task.Mode = item.Mode
+		}
+		tasks = append(tasks, task)
+		result.RestoreBytes += item.Size
+	}
+
+	if len(tasks) == 0 && len(result.Skipped) == 0 && result.Unchanged == 0 && len(result.Errors) == 0 {
+		return nil, fmt.Errorf("快照中没有匹配的文件: %s", options.Path)
+	}
+
+	transfers := a.runTransfers(ctx, syncConfigFromRule(rule), nil, tasks)
+	result.Restored = transfers.Downloaded
+	result.Errors = append(result.Errors, transfers.Errors...)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// snapshotRestoreItems 列出快照中的文件：去重快照读取清单，旧格式快照列出文件副本
+func (a *App) snapshotRestoreItems(ctx context.Context, rule SyncRule, snapshot BackupSnapshot) ([]restoreItem, error) {
+	var items []restoreItem
+
+	if snapshot.Deduplicated {
+		manifest, err := a.loadBackupManifest(ctx, snapshot.Path)
+		if err != nil {
+			return nil, err
+		}
+		root := backupRoot(rule.RemotePath)
+		for _, entry := range manifest.Files {
+			items = append(items, restoreItem{
+				RelPath: entry.Path,
+				Key:     backupDataKey(root, entry.Hash),
+				Hash:    entry.Hash,
+				Size:    entry.Size,
+				ModTime: entry.ModTime,
+				Mode:    os.FileMode(entry.Mode),
+			})
+		}
+		return items, nil
+	}
+
+	prefix := snapshot.Path + "/"
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出快照文件失败: %v", object.Err)
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		// 修改时间和权限由下载时读取的对象元数据还原
+		items = append(items, restoreItem{
+			RelPath: strings.TrimPrefix(object.Key, prefix),
+			Key:     object.Key,
+			Size:    object.Size,
+			ModTime: object.LastModified,
+		})
+	}
+	return items, nil
+}
+
+// 单个文件的恢复决定
+const (
+	restoreWrite     = iota // 需要恢复
+	restoreUnchanged        // 本地内容与快照相同
+	restoreSkip             // 本地文件比快照新，未强制覆盖
+)
+
+// shouldRestore 判断本地文件是否需要恢复
+func (a *App) shouldRestore(localPath string, item restoreItem, force bool) (int, error) {
+	info, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
+		return restoreWrite, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+	if info.IsDir() {
+		return 0, fmt.Errorf("本地路径是目录，无法恢复: %s", localPath)
+	}
+
+	if item.Hash != "" && info.Size() == item.Size {
+		checksum, err := calculateSHA256(localPath)
+		if err != nil {
+			return 0, err
+		}
+		if checksum == item.Hash {
+			return restoreUnchanged, nil
+		}
+	}
+
+	if !force && info.ModTime().After(item.ModTime) {
+		return restoreSkip, nil
+	}
+	return restoreWrite, nil
+}
+
+// restoreContent 下载快照中的文件并还原清单记录的权限和修改时间
+func (a *App) restoreContent(ctx context.Context, task transferTask) error {
+	if _, err := a.downloadFileToPath(ctx, task.RemotePath, task.LocalPath); err != nil {
+		return err
+	}
+
+	if task.Mode != 0 {
+		if err := os.Chmod(task.LocalPath, task.Mode.Perm()); err != nil {
+			return fmt.Errorf("设置文件权限失败: %v", err)
+		}
+	}
+	if !task.ModTime.IsZero() {
+		if err := os.Chtimes(task.LocalPath, task.ModTime, task.ModTime); err != nil {
+			return fmt.Errorf("设置文件修改时间失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// backupFolderPrefix 备份模式每次运行创建的文件夹前缀
+	backupFolderPrefix = "backup_"
+	// backupTimeLayout 备份文件夹名称中的时间格式
+	backupTimeLayout = "20060102_150405"
+)
+
+// BackupRetention 备份保留策略，各项为 0 时不按该项保留；全部为 0 时保留所有备份
+// 同一个备份满足任意一项即被保留（祖父-父-子轮换）
+type BackupRetention struct {
+	KeepLast    int `json:"keepLast"`    // 保留最近的 N 个备份
+	KeepDaily   int `json:"keepDaily"`   // 最近 N 天每天保留最新的一个
+	KeepWeekly  int `json:"keepWeekly"`  // 最近 N 周每周保留最新的一个
+	KeepMonthly int `json:"keepMonthly"` // 最近 N 个月每月保留最新的一个
+}
+
+// enabled 是否设置了保留策略
+func (r BackupRetention) enabled() bool {
+	return r.KeepLast > 0 || r.KeepDaily > 0 || r.KeepWeekly > 0 || r.KeepMonthly > 0
+}
+
+// validate 检查保留策略
+func (r BackupRetention) validate() error {
+	if r.KeepLast < 0 || r.KeepDaily < 0 || r.KeepWeekly < 0 || r.KeepMonthly < 0 {
+		return fmt.Errorf("备份保留数量不能为负数")
+	}
+	return nil
+}
+
+// BackupSnapshot 一次备份运行创建的快照
+type BackupSnapshot struct {
+	Name      string    `json:"name"` // 文件夹名称，如 backup_20240101_120000
+	Path      string    `json:"path"` // 远程路径
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+	FileCount int       `json:"fileCount"`
+	// 去重快照只包含清单，大小和文件数来自清单；旧格式的快照是完整的文件副本
+	Deduplicated bool `json:"deduplicated"`
+}
+
+// ListBackupSnapshots 列出同步规则已有的备份快照，按时间从新到旧排列
+func (a *App) ListBackupSnapshots(ruleID string) ([]BackupSnapshot, error) {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	return a.listBackupSnapshots(context.Background(), rule)
+}
+
+// PruneBackupSnapshots 按保留策略立即清理同步规则的旧备份，返回删除的快照数
+func (a *App) PruneBackupSnapshots(ruleID string) (int, error) {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 与同步互斥，避免删除正在进行的备份写入的内容
+	ctx, ok := a.beginSyncRun(context.Background())
+	if !ok {
+		return 0, fmt.Errorf("已有同步正在执行，请稍后再试")
+	}
+	defer a.endSyncRun()
+
+	return a.pruneBackupSnapshots(ctx, rule)
+}
+
+// SetBackupRetention 设置同步规则的备份保留策略
+func (a *App) SetBackupRetention(ruleID string, retention BackupRetention) error {
+	if err := retention.validate(); err != nil {
+		return err
+	}
+
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+	rule.Retention = retention
+	return a.UpdateSyncRule(rule)
+}
+
+// backupRoot 规则备份文件夹所在的远程路径前缀
+func backupRoot(remotePath string) string {
+	if remotePath != "" && !strings.HasSuffix(remotePath, "/") {
+		return remotePath + "/"
+	}
+	return remotePath
+}
+
+// isBackupFolder 判断文件夹名称是否为备份模式创建的快照文件夹
+func isBackupFolder(name string) bool {
+	if !strings.HasPrefix(name, backupFolderPrefix) {
+		return false
+	}
+	_, err := time.ParseInLocation(backupTimeLayout, strings.TrimPrefix(name, backupFolderPrefix), time.Local)
+	return err == nil
+}
+
+// listBackupSnapshots 汇总规则远程路径下各备份文件夹的大小和文件数
+func (a *App) listBackupSnapshots(ctx context.Context, rule SyncRule) ([]BackupSnapshot, error) {
+	if a.minioClient == nil {
+		return nil, fmt.Errorf("MinIO客户端未初始化")
+	}
+
+	root := backupRoot(rule.RemotePath)
+	snapshots := make(map[string]*BackupSnapshot)
+
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    root + backupFolderPrefix,
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出备份失败: %v", object.Err)
+		}
+
+		// 对象键的第一级目录即备份文件夹
+		name, rest, _ := strings.Cut(strings.TrimPrefix(object.Key, root), "/")
+		createdAt, err := time.ParseInLocation(backupTimeLayout, strings.TrimPrefix(name, backupFolderPrefix), time.Local)
+		if err != nil {
+			continue
+		}
+
+		snapshot, exists := snapshots[name]
+		if !exists {
+			snapshot = &BackupSnapshot{Name: name, Path: root + name, CreatedAt: createdAt}
+			snapshots[name] = snapshot
+		}
+		// 文件夹占位对象不计入文件数
+		if rest == backupManifestName {
+			snapshot.Deduplicated = true
+		} else if rest != "" && !strings.HasSuffix(rest, "/") {
+			snapshot.Size += object.Size
+			snapshot.FileCount++
+		}
+	}
+
+	result := make([]BackupSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.Deduplicated {
+			manifest, err := a.loadBackupManifest(ctx, snapshot.Path)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Size = manifest.TotalSize
+			snapshot.FileCount = len(manifest.Files)
+		}
+		result = append(result, *snapshot)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
+
+// snapshotsToKeep 按保留策略选出需要保留的快照，snapshots 必须按时间从新到旧排列
+func snapshotsToKeep(snapshots []BackupSnapshot, retention BackupRetention) map[string]bool {
+	keep := make(map[string]bool)
+
+	for i := 0; i < retention.KeepLast && i < len(snapshots); i++ {
+		keep[snapshots[i].Name] = true
+	}
+
+	// 每个时间段保留最新的一个快照，直到达到该项的保留数量
+	keepPeriods := func(count int, period func(time.Time) string) {
+		seen := make(map[string]bool)
+		for _, snapshot := range snapshots {
+			if len(seen) >= count {
+				return
+			}
+			key := period(snapshot.CreatedAt)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keep[snapshot.Name] = true
+		}
+	}
+	keepPeriods(retention.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	keepPeriods(retention.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+	keepPeriods(retention.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	return keep
+}
+
+// pruneBackupSnapshots 删除保留策略之外的备份快照，返回删除的快照数
+func (a *App) pruneBackupSnapshots(ctx context.Context, rule SyncRule) (int, error) {
+	if !rule.Retention.enabled() {
+		return 0, nil
+	}
+
+	snapshots, err := a.listBackupSnapshots(ctx, rule)
+	if err != nil {
+		return 0, err
+	}
+
+	keep := snapshotsToKeep(snapshots, rule.Retention)
+	removed := 0
+	for _, snapshot := range snapshots {
+		if keep[snapshot.Name] {
+			continue
+		}
+		if err := a.removeRemotePrefix(ctx, snapshot.Path+"/"); err != nil {
+			return removed, fmt.Errorf("删除备份 %s 失败: %v", snapshot.Name, err)
+		}
+		fmt.Printf("已删除过期备份: %s (%d 个文件, %d 字节)\n", snapshot.Path, snapshot.FileCount, snapshot.Size)
+		removed++
+	}
+
+	// 清理不再被任何快照引用的内容
+	if removed > 0 {
+		if err := a.collectBackupGarbage(ctx, rule); err != nil {
+			return removed, fmt.Errorf("清理备份内容失败: %v", err)
+		}
+	}
+	return removed, nil
+}
+
+// removeRemotePrefix 批量删除前缀下的所有对象
+func (a *App) removeRemotePrefix(ctx context.Context, prefix string) error {
+	return a.removeRemoteObjects(ctx, prefix, nil)
+}
+
+// removeRemoteObjects 批量删除前缀下的对象，keep 返回true的对象保留
+func (a *App) removeRemoteObjects(ctx context.Context, prefix string, keep func(object minio.ObjectInfo) bool) error {
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+
+	// 列表出错时停止提交删除，错误在删除结束后返回
+	var listErr error
+	toRemove := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(toRemove)
+		for object := range objectCh {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			if keep != nil && keep(object) {
+				continue
+			}
+			toRemove <- object
+		}
+	}()
+
+	// 读完所有结果，避免删除协程阻塞
+	var removeErr error
+	for result := range a.minioClient.RemoveObjects(ctx, a.minioConfig.BucketName, toRemove, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil && removeErr == nil {
+			removeErr = fmt.Errorf("删除对象 %s 失败: %v", result.ObjectName, result.Err)
+		}
+	}
+	if removeErr != nil {
+		return removeErr
+	}
+	if listErr != nil {
+		return fmt.Errorf("列出对象失败: %v", listErr)
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// backupDataFolder 备份文件内容的存放位置，按SHA-256寻址，相同内容只存一份
+	backupDataFolder = ".backup-data"
+	// backupManifestName 快照清单对象名称，位于每个备份文件夹内
+	backupManifestName = "manifest.json"
+	// backupManifestVersion 快照清单格式版本
+	backupManifestVersion = 1
+	// backupGarbageGracePeriod 最近写入的内容可能属于正在进行的备份，清理时保留
+	backupGarbageGracePeriod = 24 * time.Hour
+)
+
+// BackupManifestEntry 快照清单中的单个文件
+type BackupManifestEntry struct {
+	Path    string    `json:"path"` // 相对路径，统一使用 / 分隔
+	Hash    string    `json:"hash"` // 内容SHA-256
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Mode    uint32    `json:"mode"` // 权限位
+}
+
+// BackupManifest 快照清单，记录一次备份时每个文件对应的内容
+type BackupManifest struct {
+	Version   int                   `json:"version"`
+	RuleID    string                `json:"ruleId"`
+	LocalPath string                `json:"localPath"`
+	CreatedAt time.Time             `json:"createdAt"`
+	TotalSize int64                 `json:"totalSize"`
+	Files     []BackupManifestEntry `json:"files"`
+}
+
+// backupPlan 一次备份需要上传的新内容和生成的清单
+type backupPlan struct {
+	Manifest    *BackupManifest
+	Uploads     []transferTask
+	UploadBytes int64
+}
+
+// backupDataKey 内容对象的键，按哈希前两位分目录
+func backupDataKey(root, hash string) string {
+	return root + backupDataFolder + "/" + hash[:2] + "/" + hash
+}
+
+// planBackup 扫描本地文件生成快照清单，只有存储区中还没有的内容需要上传
+// 大小和修改时间与上一个快照相同的文件直接沿用记录的哈希，不重新读取
+func (a *App) planBackup(ctx context.Context, rule SyncRule) (*backupPlan, error) {
+	config := syncConfigFromRule(rule)
+	root := backupRoot(config.RemotePath)
+
+	localFiles, err := getAllFiles(config.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("获取本地文件列表失败: %v", err)
+	}
+
+	previous := make(map[string]BackupManifestEntry)
+	if manifest, err := a.latestBackupManifest(ctx, rule); err != nil {
+		return nil, err
+	} else if manifest != nil {
+		for _, entry := range manifest.Files {
+			previous[entry.Path] = entry
+		}
+	}
+
+	stored, err := a.storedBackupHashes(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &backupPlan{
+		Manifest: &BackupManifest{
+			Version:   backupManifestVersion,
+			RuleID:    rule.ID,
+			LocalPath: config.LocalPath,
+			CreatedAt: time.Now(),
+			Files:     []BackupManifestEntry{},
+		},
+	}
+	for _, file := range localFiles {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
+		}
+		relPath, err := syncRelPath(config.LocalPath, file)
+		if err != nil {
+			return nil, fmt.Errorf("计算相对路径失败: %v", err)
+		}
+
+		hash := ""
+		if entry, exists := previous[relPath]; exists && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			hash = entry.Hash
+		} else if hash, err = calculateSHA256(file); err != nil {
+			return nil, err
+		}
+
+		if !stored[hash] {
+			stored[hash] = true
+			plan.Uploads = append(plan.Uploads, transferTask{
+				Kind:       transferUpload,
+				RelPath:    relPath,
+				LocalPath:  file,
+				RemotePath: backupDataKey(root, hash),
+				Size:       info.Size(),
+				Checksum:   hash,
+			})
+			plan.UploadBytes += info.Size()
+		}
+
+		plan.Manifest.Files = append(plan.Manifest.Files, BackupManifestEntry{
+			Path:    relPath,
+			Hash:    hash,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Mode:    uint32(info.Mode().Perm()),
+		})
+		plan.Manifest.TotalSize += info.Size()
+	}
+
+	return plan, nil
+}
+
+// runBackup 为规则创建一个去重快照：先上传新内容，全部成功后再写入清单
+func (a *App) runBackup(ctx context.Context, rule SyncRule, status *SyncStatus) error {
+	config := syncConfigFromRule(rule)
+
+	plan, err := a.planBackup(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	result := a.runTransfers(ctx, config, nil, plan.Uploads)
+	status.FilesUploaded += result.Uploaded
+	status.Errors = append(status.Errors, result.Errors...)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("有 %d 个文件上传失败，本次不创建快照", len(result.Errors))
+	}
+
+	snapshotPath := backupRoot(config.RemotePath) + backupFolderPrefix + plan.Manifest.CreatedAt.Format(backupTimeLayout)
+	if err := a.saveBackupManifest(ctx, snapshotPath, plan.Manifest); err != nil {
+		return err
+	}
+
+	fmt.Printf("已创建快照 %s: %d 个文件, 新增内容 %d 个 (%d 字节)\n", snapshotPath, len(plan.Manifest.Files), len(plan.Uploads), plan.UploadBytes)
+	return nil
+}
+
+// uploadContent 上传备份内容并确认与扫描时的哈希一致
+// 文件在扫描后被修改时删除已上传的对象，避免内容与键不符
+func (a *App) uploadContent(ctx context.Context, task transferTask) error {
+	if _, _, _, err := a.uploadFile(ctx, task.LocalPath, task.RemotePath); err != nil {
+		return err
+	}
+	if task.Checksum == "" {
+		return nil
+	}
+
+	info, err := a.statObject(ctx, task.RemotePath, "")
+	if err != nil {
+		return fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+	expected, err := a.storedChecksum(task.RemotePath, task.Checksum)
+	if err != nil {
+		return err
+	}
+	if objectChecksum(info.UserMetadata) != expected {
+		a.minioClient.RemoveObject(context.Background(), a.minioConfig.BucketName, task.RemotePath, minio.RemoveObjectOptions{})
+		return fmt.Errorf("文件在备份过程中被修改: %s", task.LocalPath)
+	}
+	return nil
+}
+
+// saveBackupManifest 上传快照清单
+func (a *App) saveBackupManifest(ctx context.Context, snapshotPath string, manifest *BackupManifest) error {
+	data, err := a.jsonParser.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("序列化快照清单失败: %v", err)
+	}
+
+	manifestPath := snapshotPath + "/" + backupManifestName
+	sse, err := a.serverSideFor(manifestPath)
+	if err != nil {
+		return err
+	}
+	_, err = a.minioClient.PutObject(ctx, a.minioConfig.BucketName, manifestPath,
+		a.limitUpload(ctx, bytes.NewReader(data)), int64(len(data)), minio.PutObjectOptions{ContentType: "application/json", ServerSideEncryption: sse})
+	if err != nil {
+		return fmt.Errorf("上传快照清单失败: %v", err)
+	}
+	return nil
+}
+
+// loadBackupManifest 读取快照清单
+func (a *App) loadBackupManifest(ctx context.Context, snapshotPath string) (*BackupManifest, error) {
+	obj, _, err := a.getObject(ctx, snapshotPath+"/"+backupManifestName, "")
+	if err != nil {
+		return nil, fmt.Errorf("获取快照清单失败: %v", err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(a.limitDownload(ctx, obj))
+	if err != nil {
+		return nil, fmt.Errorf("读取快照清单失败: %v", err)
+	}
+
+	var manifest BackupManifest
+	if err := a.jsonParser.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析快照清单失败: %v", err)
+	}
+	return &manifest, nil
+}
+
+// latestBackupManifest 读取规则最新的去重快照清单，没有时返回nil
+// 只列出快照文件夹名称，从新到旧查找清单，不读取其他快照的内容
+func (a *App) latestBackupManifest(ctx context.Context, rule SyncRule) (*BackupManifest, error) {
+	root := backupRoot(rule.RemotePath)
+	var names []string
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix: root + backupFolderPrefix,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出备份失败: %v", object.Err)
+		}
+		if name := strings.TrimSuffix(strings.TrimPrefix(object.Key, root), "/"); isBackupFolder(name) {
+			names = append(names, name)
+		}
+	}
+
+	// 文件夹名称中的时间按字符串排序即为时间顺序
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		snapshotPath := root + name
+		if _, err := a.statObject(ctx, snapshotPath+"/"+backupManifestName, ""); err != nil {
+			// 旧格式的快照是完整的文件副本，没有清单
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				continue
+			}
+			return nil, fmt.Errorf("获取快照清单失败: %v", err)
+		}
+		return a.loadBackupManifest(ctx, snapshotPath)
+	}
+	return nil, nil
+}
+
+// storedBackupHashes 列出存储区中已有的内容哈希
+func (a *App) storedBackupHashes(ctx context.Context, root string) (map[string]bool, error) {
+	stored := make(map[string]bool)
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    root + backupDataFolder + "/",
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出备份内容失败: %v", object.Err)
+		}
+		stored[object.Key[strings.LastIndex(object.Key, "/")+1:]] = true
+	}
+	return stored, nil
+}
+
+// collectBackupGarbage 删除不再被任何快照清单引用的内容
+// 任一清单读取失败时不删除任何内容；最近写入的内容可能属于尚未写入清单的备份，保留到宽限期之后
+func (a *App) collectBackupGarbage(ctx context.Context, rule SyncRule) error {
+	snapshots, err := a.listBackupSnapshots(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		if !snapshot.Deduplicated {
+			continue
+		}
+		manifest, err := a.loadBackupManifest(ctx, snapshot.Path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range manifest.Files {
+			referenced[entry.Hash] = true
+		}
+	}
+
+	root := backupRoot(rule.RemotePath)
+	return a.removeRemoteObjects(ctx, root+backupDataFolder+"/", func(object minio.ObjectInfo) bool {
+		return referenced[object.Key[strings.LastIndex(object.Key, "/")+1:]] || time.Since(object.LastModified) <= backupGarbageGracePeriod
+	})
+}
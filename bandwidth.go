@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthSchedule 按时间段生效的限速设置
+type BandwidthSchedule struct {
+	Name          string `json:"name"`
+	Days          []int  `json:"days"`          // 生效的星期（0 表示周日），为空表示每天
+	Start         string `json:"start"`         // 开始时间，格式 HH:MM
+	End           string `json:"end"`           // 结束时间，格式 HH:MM，早于开始时间表示跨越午夜
+	UploadLimit   int64  `json:"uploadLimit"`   // 上传限速（KB/s），0 表示不限速
+	DownloadLimit int64  `json:"downloadLimit"` // 下载限速（KB/s），0 表示不限速
+}
+
+// BandwidthConfig 带宽限制配置
+type BandwidthConfig struct {
+	UploadLimit   int64               `json:"uploadLimit"`   // 默认上传限速（KB/s），0 表示不限速
+	DownloadLimit int64               `json:"downloadLimit"` // 默认下载限速（KB/s），0 表示不限速
+	Schedules     []BandwidthSchedule `json:"schedules"`     // 时间段限速，按顺序匹配第一个生效的时间段
+}
+
+// BandwidthLimits 当前生效的限速
+type BandwidthLimits struct {
+	UploadLimit   int64  `json:"uploadLimit"`
+	DownloadLimit int64  `json:"downloadLimit"`
+	Schedule      string `json:"schedule"` // 生效的时间段名称，使用默认限速时为空
+}
+
+// parseClock 解析 HH:MM 格式的时间，返回从零点开始的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间格式: %s", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// activeAt 检查时间段在指定时间是否生效
+func (s BandwidthSchedule) activeAt(now time.Time) bool {
+	if len(s.Days) > 0 {
+		dayMatched := false
+		for _, day := range s.Days {
+			if time.Weekday(day) == now.Weekday() {
+				dayMatched = true
+				break
+			}
+		}
+		if !dayMatched {
+			return false
+		}
+	}
+
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	// 跨越午夜
+	return minute >= start || minute < end
+}
+
+// limitsAt 计算指定时间生效的限速
+func (c BandwidthConfig) limitsAt(now time.Time) BandwidthLimits {
+	for _, schedule := range c.Schedules {
+		if schedule.activeAt(now) {
+			return BandwidthLimits{
+				UploadLimit:   schedule.UploadLimit,
+				DownloadLimit: schedule.DownloadLimit,
+				Schedule:      schedule.Name,
+			}
+		}
+	}
+	return BandwidthLimits{UploadLimit: c.UploadLimit, DownloadLimit: c.DownloadLimit}
+}
+
+// validateBandwidthConfig 验证带宽限制配置
+func validateBandwidthConfig(config BandwidthConfig) error {
+	if config.UploadLimit < 0 || config.DownloadLimit < 0 {
+		return fmt.Errorf("限速不能为负数")
+	}
+
+	for _, schedule := range config.Schedules {
+		if schedule.UploadLimit < 0 || schedule.DownloadLimit < 0 {
+			return fmt.Errorf("时间段 '%s' 的限速不能为负数", schedule.Name)
+		}
+		if _, err := parseClock(schedule.Start); err != nil {
+			return fmt.Errorf("时间段 '%s' 的开始时间无效: %v", schedule.Name, err)
+		}
+		if _, err := parseClock(schedule.End); err != nil {
+			return fmt.Errorf("时间段 '%s' 的结束时间无效: %v", schedule.Name, err)
+		}
+		for _, day := range schedule.Days {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("时间段 '%s' 的星期无效: %d", schedule.Name, day)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetBandwidthConfig 获取带宽限制配置
+func (a *App) GetBandwidthConfig() BandwidthConfig {
+	a.bandwidthMu.Lock()
+	defer a.bandwidthMu.Unlock()
+	return a.bandwidthConfig
+}
+
+// UpdateBandwidthConfig 更新带宽限制配置，立即对正在进行的传输生效
+func (a *App) UpdateBandwidthConfig(config BandwidthConfig) error {
+	if err := validateBandwidthConfig(config); err != nil {
+		return err
+	}
+
+	a.bandwidthMu.Lock()
+	a.bandwidthConfig = config
+	a.bandwidthMu.Unlock()
+
+	return a.saveConfig()
+}
+
+// GetCurrentBandwidthLimits 获取当前时间生效的限速
+func (a *App) GetCurrentBandwidthLimits() BandwidthLimits {
+	return a.GetBandwidthConfig().limitsAt(time.Now())
+}
+
+// currentUploadRate 当前上传限速（字节/秒）
+func (a *App) currentUploadRate() int64 {
+	return a.GetCurrentBandwidthLimits().UploadLimit * 1024
+}
+
+// currentDownloadRate 当前下载限速（字节/秒）
+func (a *App) currentDownloadRate() int64 {
+	return a.GetCurrentBandwidthLimits().DownloadLimit * 1024
+}
+
+// limitUpload 包装上传数据读取器，使其受上传限速约束，ctx 取消时等待立即结束
+func (a *App) limitUpload(ctx context.Context, r io.Reader) io.Reader {
+	return &throttledReader{ctx: ctx, reader: r, limiter: a.uploadLimiter}
+}
+
+// limitDownload 包装下载数据读取器，使其受下载限速约束，ctx 取消时等待立即结束
+func (a *App) limitDownload(ctx context.Context, r io.Reader) io.Reader {
+	return &throttledReader{ctx: ctx, reader: r, limiter: a.downloadLimiter}
+}
+
+// bandwidthLimiter 令牌桶限速器，同一方向的所有传输共享一个限速器
+// 每次读取时查询当前限速，配置修改或进入新的时间段后立即生效
+type bandwidthLimiter struct {
+	rate   func() int64 // 当前限速（字节/秒），0 表示不限速
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// newBandwidthLimiter 创建限速器
+func newBandwidthLimiter(rate func() int64) *bandwidthLimiter {
+	return &bandwidthLimiter{rate: rate}
+}
+
+// wait 消耗 n 字节的令牌，令牌不足时等待；ctx 取消时返回 ctx 的错误
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	for n > 0 {
+		rate := l.rate()
+		if rate <= 0 {
+			return nil
+		}
+
+		// 令牌桶容量为一秒的流量
+		burst := float64(rate)
+		take := float64(n)
+		if take > burst {
+			take = burst
+		}
+
+		l.mu.Lock()
+		now := time.Now()
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * float64(rate)
+		}
+		if l.tokens > burst || l.last.IsZero() {
+			l.tokens = burst
+		}
+		l.last = now
+		l.tokens -= take
+		deficit := -l.tokens
+		l.mu.Unlock()
+
+		// 令牌不足时按欠缺的字节数等待，并发读取会依次排队
+		if deficit > 0 {
+			timer := time.NewTimer(time.Duration(deficit / float64(rate) * float64(time.Second)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		n -= int(take)
+	}
+	return nil
+}
+
+// throttledReader 受限速约束的读取器
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *bandwidthLimiter
+}
+
+// Read 实现 io.Reader
+func (r *throttledReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 && r.limiter != nil {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
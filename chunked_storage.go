@@ -0,0 +1,574 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// 分块存储：大文件按内容定义的边界切分，远程对象只保存分块清单，分块内容按哈希寻址单独存放
+// 文件局部修改只影响附近的分块，上传和下载时只传输变化的分块
+
+const (
+	// chunkStoreFolder 规则远程根目录下存放分块内容的目录，同步时跳过
+	chunkStoreFolder = ".acloud-chunks"
+	// chunkedMetadataKey 分块清单对象写入用户元数据的格式版本，下载时据此按清单组装文件
+	chunkedMetadataKey = "Chunked"
+	chunkedFormat      = "v1"
+	// defaultChunkingMinFileSize 未设置时启用分块存储的最小文件大小
+	defaultChunkingMinFileSize = 16 * 1024 * 1024
+	// chunkGracePeriod 最近写入的分块可能属于正在上传的文件，清理时保留
+	chunkGracePeriod = 24 * time.Hour
+)
+
+// 分块大小范围，平均约1MiB
+const (
+	minChunkSize  = 256 * 1024
+	maxChunkSize  = 4 * 1024 * 1024
+	chunkHashMask = 1<<20 - 1
+)
+
+// chunkGear 滚动哈希使用的随机表，由固定种子生成，所有设备切分结果一致
+var chunkGear = func() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		sum := sha256.Sum256([]byte("acloud chunk gear " + strconv.Itoa(i)))
+		table[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+	return table
+}()
+
+// RuleChunking 同步规则的分块存储设置
+type RuleChunking struct {
+	Enabled     bool  `json:"enabled"`
+	MinFileSize int64 `json:"minFileSize"` // 达到该大小的文件使用分块存储，0 表示默认的16MiB
+}
+
+// validate 检查分块存储设置
+func (c RuleChunking) validate() error {
+	if c.MinFileSize < 0 {
+		return fmt.Errorf("分块存储的最小文件大小不能为负数")
+	}
+	return nil
+}
+
+// minFileSize 实际使用的最小文件大小
+func (c RuleChunking) minFileSize() int64 {
+	if c.MinFileSize == 0 {
+		return defaultChunkingMinFileSize
+	}
+	return c.MinFileSize
+}
+
+// ChunkManifest 分块清单，按顺序拼接各分块即为文件内容
+type ChunkManifest struct {
+	Version int        `json:"version"`
+	Size    int64      `json:"size"`
+	Chunks  []ChunkRef `json:"chunks"`
+}
+
+// ChunkRef 清单中的单个分块
+type ChunkRef struct {
+	Hash string `json:"hash"` // 分块内容SHA-256
+	Size int64  `json:"size"`
+	Key  string `json:"key"` // 分块对象键，文件移动到其他路径后仍可找到分块
+}
+
+// chunker 按内容定义的边界切分数据
+type chunker struct {
+	r      io.Reader
+	buf    []byte
+	filled int
+	eof    bool
+}
+
+// newChunker 创建切分器
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, maxChunkSize)}
+}
+
+// next 返回下一个分块，没有更多数据时返回 io.EOF
+func (c *chunker) next() ([]byte, error) {
+	if !c.eof && c.filled < len(c.buf) {
+		n, err := io.ReadFull(c.r, c.buf[c.filled:])
+		c.filled += n
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.filled == 0 {
+		return nil, io.EOF
+	}
+
+	cut := chunkBoundary(c.buf[:c.filled])
+	chunk := make([]byte, cut)
+	copy(chunk, c.buf[:cut])
+	c.filled = copy(c.buf, c.buf[cut:c.filled])
+	return chunk, nil
+}
+
+// chunkBoundary 在数据中查找分块边界，返回分块长度
+func chunkBoundary(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	limit := len(data)
+	if limit > maxChunkSize {
+		limit = maxChunkSize
+	}
+
+	var h uint64
+	for i := minChunkSize; i < limit; i++ {
+		h = h<<1 + chunkGear[data[i]]
+		if h&chunkHashMask == 0 {
+			return i + 1
+		}
+	}
+	return limit
+}
+
+// chunkingRuleFor 判断上传到对象键的文件是否使用分块存储
+func (a *App) chunkingRuleFor(remotePath string, size int64) (SyncRule, bool) {
+	rule, ok := a.ruleForKey(remotePath, func(rule SyncRule) bool { return rule.Chunking.Enabled })
+	if !ok || size < rule.Chunking.minFileSize() {
+		return SyncRule{}, false
+	}
+	return rule, true
+}
+
+// chunkKey 分块对象键；加密规则使用带密钥的哈希命名，不暴露内容哈希
+func chunkKey(rule SyncRule, hash string, enc *ruleCipher) string {
+	if enc != nil {
+		hash = enc.checksum(hash)
+	}
+	return remoteWatchPrefix(rule.RemotePath) + chunkStoreFolder + "/" + hash[:2] + "/" + hash
+}
+
+// isChunkKey 判断是否为分块存储中的对象
+func isChunkKey(key string) bool {
+	return strings.HasPrefix(key, chunkStoreFolder+"/") || strings.Contains(key, "/"+chunkStoreFolder+"/")
+}
+
+// isChunked 判断对象是否为分块清单
+func isChunked(info minio.ObjectInfo) bool {
+	return metadataValue(info.UserMetadata, chunkedMetadataKey) != ""
+}
+
+// objectPlainSize 对象还原后的文件大小：压缩、加密和分块存储的对象读取元数据中记录的原始大小
+func objectPlainSize(info minio.ObjectInfo) int64 {
+	if value := metadataValue(info.UserMetadata, plainSizeMetadataKey); value != "" {
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return size
+		}
+	}
+	return info.Size
+}
+
+// uploadChunked 以分块方式上传文件：远程已有的分块跳过，最后上传清单替换远程对象
+// 中断后重新上传时已完成的分块不再传输；返回清单大小，用于上传校验
+func (a *App) uploadChunked(ctx context.Context, rule SyncRule, enc *ruleCipher, file *os.File, fileInfo os.FileInfo, localPath, remotePath, checksum string) (minio.UploadInfo, int64, error) {
+	algorithm, err := a.compressionFor(remotePath, localPath)
+	if err != nil {
+		return minio.UploadInfo{}, 0, err
+	}
+
+	// 上一版清单引用的分块一定存在，无需逐个查询
+	known := make(map[string]bool)
+	if previous, err := a.loadChunkManifest(ctx, remotePath, ""); err == nil && previous != nil {
+		for _, chunk := range previous.Chunks {
+			known[chunk.Key] = true
+		}
+	}
+
+	progress := a.newTransferProgress(remotePath, fileInfo.Size())
+	manifest := &ChunkManifest{Version: 1, Size: fileInfo.Size()}
+	split := newChunker(bufio.NewReaderSize(file, maxChunkSize))
+	for {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return minio.UploadInfo{}, 0, err
+		}
+
+		data, err := split.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return minio.UploadInfo{}, 0, fmt.Errorf("读取文件失败: %v", err)
+		}
+
+		sum := sha256.Sum256(data)
+		chunk := ChunkRef{Hash: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+		chunk.Key = chunkKey(rule, chunk.Hash, enc)
+		if !known[chunk.Key] {
+			if err := a.putChunk(ctx, enc, chunk, data, algorithm); err != nil {
+				return minio.UploadInfo{}, 0, err
+			}
+			known[chunk.Key] = true
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk)
+		progress.add(chunk.Size)
+	}
+
+	data, err := a.jsonParser.Marshal(manifest)
+	if err != nil {
+		return minio.UploadInfo{}, 0, fmt.Errorf("序列化分块清单失败: %v", err)
+	}
+	metadata := fileMetadata(fileInfo, checksum, a.preserveOwner(remotePath))
+	metadata[chunkedMetadataKey] = chunkedFormat
+	metadata[plainSizeMetadataKey] = strconv.FormatInt(fileInfo.Size(), 10)
+
+	var info minio.UploadInfo
+	if enc != nil {
+		info, err = a.putEncrypted(ctx, enc, remotePath, bytes.NewReader(data), int64(len(data)), metadata, checksum, nil)
+	} else {
+		info, err = a.putObjectBytes(ctx, remotePath, data, metadata)
+	}
+	if err != nil {
+		return minio.UploadInfo{}, 0, err
+	}
+	return info, int64(len(data)), nil
+}
+
+// putChunk 上传远程还没有的分块，分块按规则设置压缩和加密
+func (a *App) putChunk(ctx context.Context, enc *ruleCipher, chunk ChunkRef, data []byte, algorithm string) error {
+	if _, err := a.statObject(ctx, chunk.Key, ""); err == nil {
+		return nil
+	} else if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("获取分块信息失败: %v", err)
+	}
+
+	metadata := checksumMetadata(chunk.Hash)
+	if algorithm != "" {
+		var compressed bytes.Buffer
+		if err := compressTo(&compressed, bytes.NewReader(data), algorithm); err != nil {
+			return err
+		}
+		if compressed.Len() < len(data) {
+			data = compressed.Bytes()
+			metadata[compressionMetadataKey] = algorithm
+			metadata[plainSizeMetadataKey] = strconv.FormatInt(chunk.Size, 10)
+		}
+	}
+
+	var err error
+	if enc != nil {
+		_, err = a.putEncrypted(ctx, enc, chunk.Key, bytes.NewReader(data), int64(len(data)), metadata, chunk.Hash, nil)
+	} else {
+		_, err = a.putObjectBytes(ctx, chunk.Key, data, metadata)
+	}
+	if err != nil {
+		return fmt.Errorf("上传分块失败: %v", err)
+	}
+	return nil
+}
+
+// putObjectBytes 上传内存中的内容
+func (a *App) putObjectBytes(ctx context.Context, remotePath string, data []byte, metadata map[string]string) (minio.UploadInfo, error) {
+	sse, err := a.serverSideFor(remotePath)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	info, err := a.minioClient.PutObject(ctx, a.minioConfig.BucketName, remotePath, a.limitUpload(ctx, bytes.NewReader(data)), int64(len(data)), minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		UserMetadata:         metadata,
+		ServerSideEncryption: sse,
+	})
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("上传文件失败: %v", err)
+	}
+	return info, nil
+}
+
+// loadChunkManifest 读取对象的分块清单，对象不是分块清单时返回nil
+func (a *App) loadChunkManifest(ctx context.Context, remotePath, versionID string) (*ChunkManifest, error) {
+	obj, info, err := a.getObject(ctx, remotePath, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	if !isChunked(info) {
+		return nil, nil
+	}
+
+	content, _, err := a.objectContent(remotePath, info, a.limitDownload(ctx, obj))
+	if err != nil {
+		return nil, err
+	}
+	return a.parseChunkManifest(content)
+}
+
+// parseChunkManifest 解析分块清单
+func (a *App) parseChunkManifest(r io.Reader) (*ChunkManifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取分块清单失败: %v", err)
+	}
+	var manifest ChunkManifest
+	if err := a.jsonParser.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析分块清单失败: %v", err)
+	}
+	return &manifest, nil
+}
+
+// objectReader 读取对象内容，关闭时释放对象
+type objectReader struct {
+	io.Reader
+	io.Closer
+}
+
+// openObject 打开对象并返回还原后的文件内容：加密对象解密，压缩对象解压，分块清单按顺序读取各分块
+// localPath 不为空时，本地文件中已有的分块直接从本地读取，不再下载
+func (a *App) openObject(ctx context.Context, remotePath, versionID, localPath string) (io.ReadCloser, minio.ObjectInfo, *ruleCipher, error) {
+	obj, info, err := a.getObject(ctx, remotePath, versionID)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, nil, err
+	}
+
+	content, enc, err := a.objectContent(remotePath, info, a.limitDownload(ctx, obj))
+	if err != nil {
+		obj.Close()
+		return nil, minio.ObjectInfo{}, nil, err
+	}
+	if !isChunked(info) {
+		return objectReader{Reader: content, Closer: obj}, info, enc, nil
+	}
+
+	manifest, err := a.parseChunkManifest(content)
+	obj.Close()
+	if err != nil {
+		return nil, minio.ObjectInfo{}, nil, err
+	}
+	reader, err := a.newChunkedReader(ctx, manifest, localPath)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, nil, err
+	}
+	return reader, info, enc, nil
+}
+
+// localChunk 本地文件中的分块位置
+type localChunk struct {
+	offset int64
+	size   int64
+}
+
+// chunkedReader 按清单顺序读取分块，每个分块读完后校验哈希
+type chunkedReader struct {
+	app      *App
+	ctx      context.Context
+	chunks   []ChunkRef
+	next     int
+	local    *os.File
+	localMap map[string]localChunk
+	current  io.Reader
+	closer   io.Closer
+	hash     hash.Hash
+}
+
+// newChunkedReader 创建分块读取器，localPath 存在时先切分本地文件，记录可复用的分块
+func (a *App) newChunkedReader(ctx context.Context, manifest *ChunkManifest, localPath string) (*chunkedReader, error) {
+	reader := &chunkedReader{app: a, ctx: ctx, chunks: manifest.Chunks, hash: sha256.New()}
+	if localPath == "" {
+		return reader, nil
+	}
+
+	file, err := os.Open(localPath)
+	if os.IsNotExist(err) {
+		return reader, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开本地文件失败: %v", err)
+	}
+
+	localMap := make(map[string]localChunk)
+	split := newChunker(bufio.NewReaderSize(file, maxChunkSize))
+	var offset int64
+	for {
+		data, err := split.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("读取本地文件失败: %v", err)
+		}
+		sum := sha256.Sum256(data)
+		localMap[hex.EncodeToString(sum[:])] = localChunk{offset: offset, size: int64(len(data))}
+		offset += int64(len(data))
+	}
+
+	reader.local = file
+	reader.localMap = localMap
+	return reader, nil
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next == len(r.chunks) {
+				return 0, io.EOF
+			}
+			if err := r.open(r.chunks[r.next]); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if n > 0 {
+			r.hash.Write(p[:n])
+			return n, nil
+		}
+		if err != io.EOF {
+			return 0, err
+		}
+
+		// 分块读完，校验内容
+		chunk := r.chunks[r.next]
+		r.closeCurrent()
+		if hex.EncodeToString(r.hash.Sum(nil)) != chunk.Hash {
+			return 0, fmt.Errorf("分块内容校验失败: %s", chunk.Key)
+		}
+		r.next++
+	}
+}
+
+// open 打开分块：本地已有时从本地文件读取，否则下载分块对象
+func (r *chunkedReader) open(chunk ChunkRef) error {
+	r.hash.Reset()
+	if local, exists := r.localMap[chunk.Hash]; exists && local.size == chunk.Size {
+		r.current = io.NewSectionReader(r.local, local.offset, local.size)
+		return nil
+	}
+
+	obj, info, err := r.app.getObject(r.ctx, chunk.Key, "")
+	if err != nil {
+		return fmt.Errorf("获取分块失败: %v", err)
+	}
+	content, _, err := r.app.objectContent(chunk.Key, info, r.app.limitDownload(r.ctx, obj))
+	if err != nil {
+		obj.Close()
+		return err
+	}
+	r.current = content
+	r.closer = obj
+	return nil
+}
+
+// closeCurrent 关闭当前分块
+func (r *chunkedReader) closeCurrent() {
+	if r.closer != nil {
+		r.closer.Close()
+	}
+	r.current = nil
+	r.closer = nil
+}
+
+// Close 关闭当前分块和本地文件
+func (r *chunkedReader) Close() error {
+	r.closeCurrent()
+	if r.local != nil {
+		return r.local.Close()
+	}
+	return nil
+}
+
+// PruneChunks 删除规则分块存储中不再被任何文件引用的分块，返回删除的分块数
+// 存储桶中所有对象的所有版本（包括回收站）引用的分块都会保留；任一清单读取失败时不删除任何分块
+func (a *App) PruneChunks(ruleID string) (int, error) {
+	if !a.isLoggedIn {
+		return 0, fmt.Errorf("用户未登录")
+	}
+	if !a.minioConfig.Enabled || a.minioClient == nil {
+		return 0, fmt.Errorf("MinIO 未启用")
+	}
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	referenced, err := a.referencedChunks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// 先确定要删除的分块，最近写入的分块可能属于正在上传、清单尚未写入的文件
+	prefix := remoteWatchPrefix(rule.RemotePath) + chunkStoreFolder + "/"
+	unused := make(map[string]bool)
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return 0, fmt.Errorf("列出分块失败: %v", object.Err)
+		}
+		if !referenced[object.Key] && time.Since(object.LastModified) > chunkGracePeriod {
+			unused[object.Key] = true
+		}
+	}
+	if len(unused) == 0 {
+		return 0, nil
+	}
+
+	if err := a.removeRemoteObjects(ctx, prefix, func(object minio.ObjectInfo) bool { return !unused[object.Key] }); err != nil {
+		return 0, err
+	}
+	return len(unused), nil
+}
+
+// referencedChunks 读取存储桶中所有分块清单，汇总引用的分块对象键
+func (a *App) referencedChunks(ctx context.Context) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Recursive:    true,
+		WithVersions: true,
+		WithMetadata: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出对象失败: %v", object.Err)
+		}
+		if object.IsDeleteMarker || isChunkKey(object.Key) || strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+
+		// 服务端不支持在列表中返回元数据时逐个查询
+		metadata := object.UserMetadata
+		if metadata == nil {
+			info, err := a.statObject(ctx, object.Key, object.VersionID)
+			if err != nil {
+				return nil, fmt.Errorf("获取对象信息失败: %v", err)
+			}
+			metadata = info.UserMetadata
+		}
+		if metadataValue(metadata, chunkedMetadataKey) == "" {
+			continue
+		}
+
+		manifest, err := a.loadChunkManifest(ctx, object.Key, object.VersionID)
+		if err != nil {
+			return nil, fmt.Errorf("读取分块清单 %s 失败: %v", object.Key, err)
+		}
+		if manifest == nil {
+			continue
+		}
+		for _, chunk := range manifest.Chunks {
+			referenced[chunk.Key] = true
+		}
+	}
+	return referenced, nil
+}
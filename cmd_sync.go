@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -55,6 +57,42 @@ func (a *App) RunSyncCommand() {
 			a.cmdSyncConflicts()
 		case "resolve":
 			a.cmdResolveConflict()
+		case "verify":
+			a.cmdVerifySync()
+		case "snapshots":
+			a.cmdListSnapshots()
+		case "prune":
+			a.cmdPruneSnapshots()
+		case "retention":
+			a.cmdSetRetention()
+		case "restore":
+			a.cmdRestoreSnapshot()
+		case "versioning":
+			a.cmdBucketVersioning()
+		case "versions":
+			a.cmdListFileVersions()
+		case "restore-version":
+			a.cmdRestoreFileVersion()
+		case "download-version":
+			a.cmdDownloadFileVersion()
+		case "preserve-owner":
+			a.cmdSetPreserveOwner()
+		case "encrypt":
+			a.cmdEncryptRule()
+		case "unlock":
+			a.cmdUnlockRule()
+		case "lock":
+			a.cmdLockRule()
+		case "compress":
+			a.cmdSetCompression()
+		case "chunking":
+			a.cmdSetChunking()
+		case "prune-chunks":
+			a.cmdPruneChunks()
+		case "sse":
+			a.cmdServerEncryption()
+		case "sse-key":
+			a.cmdExportServerEncryptionKey()
 		default:
 			a.showSyncHelp()
 		}
@@ -73,7 +111,7 @@ func (a *App) showSyncHelp() {
 	fmt.Println("  start                         - 启动同步服务")
 	fmt.Println("  stop                          - 停止同步服务")
 	fmt.Println("  status                        - 显示同步状态")
-	fmt.Println("  run [mode]                    - 执行一次同步 (mode: full, selective, backup, incremental)")
+	fmt.Println("  run [--dry-run] [mode]        - 执行一次同步 (mode: full, selective, backup, incremental)，--dry-run 只显示同步计划")
 	fmt.Println("  add-rule <名称> <本地路径> <远程路径> <方向> - 添加同步规则")
 	fmt.Println("  list-rules                    - 列出同步规则")
 	fmt.Println("  remove-rule <ID>              - 删除同步规则")
@@ -82,6 +120,25 @@ func (a *App) showSyncHelp() {
 	fmt.Println("  history                       - 显示同步历史")
 	fmt.Println("  conflicts                     - 显示同步冲突")
 	fmt.Println("  resolve <路径> <解决方式>      - 解决同步冲突 (local, remote, both, skip)")
+	fmt.Println("  verify <规则ID或名称> [--deep] [--repair=upload|download] - 检查本地与远程文件完整性，--deep 完整读取远程对象")
+	fmt.Println("  snapshots <规则ID或名称>      - 列出备份快照")
+	fmt.Println("  prune <规则ID或名称>          - 按保留策略清理旧备份")
+	fmt.Println("  retention <规则ID或名称> [--keep-last=N] [--keep-daily=N] [--keep-weekly=N] [--keep-monthly=N] - 设置备份保留策略")
+	fmt.Println("  restore <规则ID或名称> <快照|latest> [--path 子目录] [--to 目录] [--force] - 从备份快照恢复文件，--force 覆盖更新的本地文件")
+	fmt.Println("  versioning [enable|suspend]   - 显示或设置存储桶版本控制")
+	fmt.Println("  versions <远程路径>           - 列出远程文件的历史版本")
+	fmt.Println("  restore-version <远程路径> <版本ID> - 将远程文件恢复到指定版本")
+	fmt.Println("  download-version <远程路径> <版本ID> [本地路径] - 下载远程文件的指定版本")
+	fmt.Println("  preserve-owner <规则ID或名称> <on|off> - 设置是否记录并还原文件所有者（uid:gid），只有以root运行时才会还原")
+	fmt.Println("  encrypt <规则ID或名称> [--names] - 为规则启用客户端加密（远程目录需为空），--names 同时加密文件名")
+	fmt.Println("  unlock <规则ID或名称>         - 输入密码解锁加密规则，密钥保存在本机")
+	fmt.Println("  lock <规则ID或名称>           - 锁定加密规则并删除本机保存的密钥")
+	fmt.Println("  加密密码从环境变量 ACLOUD_PASSPHRASE 读取，未设置时从标准输入读取一行")
+	fmt.Println("  compress <规则ID或名称> <zstd|gzip|off> [--ext=.log,.csv] [--probe] - 设置上传压缩，--probe 对其他文件先探测压缩率")
+	fmt.Println("  chunking <规则ID或名称> <on|off> [--min-size=MB] - 设置大文件分块存储，只传输文件中变化的部分")
+	fmt.Println("  prune-chunks <规则ID或名称>   - 删除不再被任何文件引用的分块")
+	fmt.Println("  sse [none|SSE-S3|SSE-C|inherit] [--rule=<规则ID或名称>] [--key=<Base64密钥>] - 显示或设置服务端加密，inherit 表示规则使用全局设置")
+	fmt.Println("  sse-key [规则ID或名称]        - 导出 SSE-C 密钥，其他设备需要导入相同的密钥")
 }
 
 // cmdStartSync 启动同步服务
@@ -121,8 +178,19 @@ func (a *App) cmdSyncStatus() {
 // cmdRunSync 执行一次同步
 func (a *App) cmdRunSync() {
 	mode := "full"
-	if len(os.Args) >= 4 {
-		mode = os.Args[3]
+	dryRun := false
+	for _, arg := range os.Args[3:] {
+		if arg == "--dry-run" {
+			dryRun = true
+		} else {
+			mode = arg
+		}
+	}
+
+	// 只预览同步计划，不执行传输
+	if dryRun {
+		a.cmdPreviewSync(mode)
+		return
 	}
 
 	// 设置同步模式
@@ -140,6 +208,41 @@ func (a *App) cmdRunSync() {
 	time.Sleep(2 * time.Second)
 }
 
+// cmdPreviewSync 显示所有启用规则的同步计划
+func (a *App) cmdPreviewSync(mode string) {
+	fmt.Printf("%s同步计划（不会传输任何文件）:\n", mode)
+
+	for _, rule := range a.GetSyncRules() {
+		if !rule.Enabled {
+			continue
+		}
+
+		plan, err := a.planSync(rule, mode)
+		if err != nil {
+			fmt.Printf("\n规则 '%s' 生成同步计划失败: %v\n", rule.Name, err)
+			continue
+		}
+
+		fmt.Printf("\n规则 '%s' (%s):\n", rule.Name, rule.Direction)
+		for _, item := range plan.Items {
+			target := item.Path
+			if item.NewPath != "" {
+				target = item.Path + " -> " + item.NewPath
+			}
+			if item.Reason != "" {
+				fmt.Printf("  %-14s %s (%d 字节, %s)\n", item.Action, target, item.Size, item.Reason)
+			} else {
+				fmt.Printf("  %-14s %s (%d 字节)\n", item.Action, target, item.Size)
+			}
+		}
+		fmt.Printf("  合计: 上传 %d 个 (%d 字节), 下载 %d 个 (%d 字节), 删除 %d 个, 重命名 %d 个, 冲突 %d 个\n",
+			plan.Uploads, plan.UploadBytes, plan.Downloads, plan.DownloadBytes, plan.Deletes, plan.Renames, plan.Conflicts)
+		if plan.DeletionBlocked != "" {
+			fmt.Printf("  警告: %s\n", plan.DeletionBlocked)
+		}
+	}
+}
+
 // cmdAddSyncRule 添加同步规则
 func (a *App) cmdAddSyncRule() {
 	if len(os.Args) < 7 {
@@ -216,6 +319,48 @@ func (a *App) cmdListSyncRules() {
 		fmt.Printf("   远程路径: %s\n", rule.RemotePath)
 		fmt.Printf("   方向: %s\n", rule.Direction)
 
+		if rule.Direction == "bidirectional" {
+			fmt.Printf("   删除保护阈值: %d%%\n", effectiveMaxDeletePercent(rule.MaxDeletePercent))
+		}
+
+		if rule.Concurrency > 0 {
+			fmt.Printf("   并发传输数: %d\n", rule.Concurrency)
+		}
+
+		if rule.PreserveOwner {
+			fmt.Println("   还原文件所有者: 是")
+		}
+
+		if rule.ServerEncryption.Mode != ServerEncryptionInherit {
+			fmt.Printf("   服务端加密: %s\n", rule.ServerEncryption.Mode)
+		}
+
+		if rule.Compression.Enabled {
+			extensions := "默认文本类文件"
+			if len(rule.Compression.Extensions) > 0 {
+				extensions = strings.Join(rule.Compression.Extensions, ",")
+			}
+			if rule.Compression.Probe {
+				extensions += ", 探测其他文件"
+			}
+			fmt.Printf("   上传压缩: %s (%s)\n", rule.Compression.algorithm(), extensions)
+		}
+
+		if rule.Chunking.Enabled {
+			fmt.Printf("   分块存储: 不小于 %d MB 的文件\n", rule.Chunking.minFileSize()/(1024*1024))
+		}
+
+		if rule.Encryption.Enabled {
+			state := "已锁定"
+			if a.IsRuleEncryptionUnlocked(rule.ID) {
+				state = "已解锁"
+			}
+			if rule.Encryption.EncryptNames {
+				state += ", 加密文件名"
+			}
+			fmt.Printf("   客户端加密: %s\n", state)
+		}
+
 		if len(rule.Filters) > 0 {
 			fmt.Printf("   过滤器: %s\n", strings.Join(rule.Filters, ", "))
 		}
@@ -370,6 +515,592 @@ func (a *App) cmdResolveConflict() {
 	fmt.Printf("已解决冲突: %s (使用%s)\n", path, resolution)
 }
 
+// cmdVerifySync 检查同步规则的完整性，可按指定方向修复
+func (a *App) cmdVerifySync() {
+	var target, repair string
+	deep := false
+	for _, arg := range os.Args[3:] {
+		switch {
+		case arg == "--deep":
+			deep = true
+		case strings.HasPrefix(arg, "--repair="):
+			repair = strings.TrimPrefix(arg, "--repair=")
+		default:
+			target = arg
+		}
+	}
+
+	if target == "" {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync verify <规则ID或名称> [--deep] [--repair=upload|download]")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(target)
+
+	fmt.Printf("正在检查规则 '%s'...\n", rule.Name)
+	report, err := a.VerifySyncRule(rule.ID, repair, deep)
+	if err != nil {
+		fmt.Printf("完整性检查失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	unresolved := 0
+	for _, issue := range report.Issues {
+		switch {
+		case issue.Repaired:
+			fmt.Printf("  %-14s %s (%s, 已修复)\n", issue.Status, issue.Path, issue.Detail)
+		case issue.RepairError != "":
+			fmt.Printf("  %-14s %s (%s, 修复失败: %s)\n", issue.Status, issue.Path, issue.Detail, issue.RepairError)
+			unresolved++
+		default:
+			fmt.Printf("  %-14s %s (%s)\n", issue.Status, issue.Path, issue.Detail)
+			unresolved++
+		}
+	}
+	fmt.Printf("检查 %d 个文件: 正常 %d 个, 问题 %d 个, 已修复 %d 个\n", report.Checked, report.OK, len(report.Issues), report.Repaired)
+
+	// 有未解决的问题时以非零状态退出，便于定时任务报警
+	if unresolved > 0 {
+		os.Exit(1)
+	}
+}
+
+// cmdFindRule 按ID或名称查找同步规则，找不到时退出
+func (a *App) cmdFindRule(target string) SyncRule {
+	if rule, err := a.GetSyncRuleByID(target); err == nil {
+		return rule
+	}
+	rule, err := a.GetSyncRuleByName(target)
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		os.Exit(1)
+	}
+	return rule
+}
+
+// cmdListSnapshots 列出规则的备份快照
+func (a *App) cmdListSnapshots() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync snapshots <规则ID或名称>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	snapshots, err := a.ListBackupSnapshots(rule.ID)
+	if err != nil {
+		fmt.Printf("列出备份快照失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Printf("规则 '%s' 没有备份快照\n", rule.Name)
+		return
+	}
+
+	keep := snapshotsToKeep(snapshots, rule.Retention)
+	fmt.Printf("规则 '%s' 的备份快照:\n", rule.Name)
+	for _, snapshot := range snapshots {
+		mark := ""
+		if !snapshot.Deduplicated {
+			mark += " (完整副本)"
+		}
+		if rule.Retention.enabled() && !keep[snapshot.Name] {
+			mark += " (将被清理)"
+		}
+		fmt.Printf("  %s  %s  %d 个文件, %d 字节%s\n",
+			snapshot.CreatedAt.Format("2006-01-02 15:04:05"), snapshot.Path, snapshot.FileCount, snapshot.Size, mark)
+	}
+	fmt.Printf("保留策略: 最近 %d 个, 每天 %d 个, 每周 %d 个, 每月 %d 个\n",
+		rule.Retention.KeepLast, rule.Retention.KeepDaily, rule.Retention.KeepWeekly, rule.Retention.KeepMonthly)
+}
+
+// cmdPruneSnapshots 按保留策略清理规则的旧备份
+func (a *App) cmdPruneSnapshots() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync prune <规则ID或名称>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	if !rule.Retention.enabled() {
+		fmt.Printf("规则 '%s' 未设置保留策略，不会清理备份\n", rule.Name)
+		return
+	}
+
+	removed, err := a.PruneBackupSnapshots(rule.ID)
+	if err != nil {
+		fmt.Printf("清理旧备份失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已清理 %d 个旧备份\n", removed)
+}
+
+// cmdSetRetention 设置规则的备份保留策略
+func (a *App) cmdSetRetention() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync retention <规则ID或名称> [--keep-last=N] [--keep-daily=N] [--keep-weekly=N] [--keep-monthly=N]")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	retention := rule.Retention
+	for _, arg := range os.Args[4:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			fmt.Printf("错误: 无效的参数: %s\n", arg)
+			os.Exit(1)
+		}
+		switch name {
+		case "keep-last":
+			retention.KeepLast = count
+		case "keep-daily":
+			retention.KeepDaily = count
+		case "keep-weekly":
+			retention.KeepWeekly = count
+		case "keep-monthly":
+			retention.KeepMonthly = count
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	if err := a.SetBackupRetention(rule.ID, retention); err != nil {
+		fmt.Printf("设置保留策略失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("规则 '%s' 的保留策略: 最近 %d 个, 每天 %d 个, 每周 %d 个, 每月 %d 个\n",
+		rule.Name, retention.KeepLast, retention.KeepDaily, retention.KeepWeekly, retention.KeepMonthly)
+}
+
+// cmdRestoreSnapshot 从备份快照恢复文件
+func (a *App) cmdRestoreSnapshot() {
+	usage := "用法: acloud sync restore <规则ID或名称> <快照|latest> [--path 子目录] [--to 目录] [--force]"
+	if len(os.Args) < 5 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	snapshot := os.Args[4]
+
+	var options RestoreOptions
+	args := os.Args[5:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--force":
+			options.Force = true
+		case "--path", "--to":
+			if i+1 >= len(args) {
+				fmt.Printf("错误: %s 缺少参数值\n", args[i])
+				fmt.Println(usage)
+				os.Exit(1)
+			}
+			if args[i] == "--path" {
+				options.Path = args[i+1]
+			} else {
+				options.Target = args[i+1]
+			}
+			i++
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", args[i])
+			fmt.Println(usage)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("正在从快照 %s 恢复规则 '%s'...\n", snapshot, rule.Name)
+	result, err := a.RestoreSnapshot(rule.ID, snapshot, options)
+	if err != nil {
+		fmt.Printf("恢复失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, path := range result.Skipped {
+		fmt.Printf("  跳过 %s (本地文件比快照新，使用 --force 覆盖)\n", path)
+	}
+	for _, message := range result.Errors {
+		fmt.Printf("  错误: %s\n", message)
+	}
+	fmt.Printf("已从 %s 恢复到 %s: 恢复 %d 个文件, 未变化 %d 个, 跳过 %d 个, 错误 %d 个\n",
+		result.Snapshot, result.Target, result.Restored, result.Unchanged, len(result.Skipped), len(result.Errors))
+
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+// cmdBucketVersioning 显示或设置存储桶版本控制
+func (a *App) cmdBucketVersioning() {
+	if len(os.Args) >= 4 {
+		switch os.Args[3] {
+		case "enable":
+			if err := a.SetBucketVersioning(true); err != nil {
+				fmt.Printf("启用版本控制失败: %v\n", err)
+				os.Exit(1)
+			}
+		case "suspend":
+			if err := a.SetBucketVersioning(false); err != nil {
+				fmt.Printf("暂停版本控制失败: %v\n", err)
+				os.Exit(1)
+			}
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", os.Args[3])
+			fmt.Println("用法: acloud sync versioning [enable|suspend]")
+			os.Exit(1)
+		}
+	}
+
+	status, err := a.GetBucketVersioning()
+	if err != nil {
+		fmt.Printf("获取版本控制状态失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("存储桶 '%s' 版本控制: %s\n", a.minioConfig.BucketName, status)
+}
+
+// cmdListFileVersions 列出远程文件的历史版本
+func (a *App) cmdListFileVersions() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync versions <远程路径>")
+		os.Exit(1)
+	}
+
+	versions, err := a.ListFileVersions(os.Args[3])
+	if err != nil {
+		fmt.Printf("列出文件版本失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("文件 '%s' 的历史版本:\n", versions[0].Path)
+	for _, version := range versions {
+		mark := ""
+		if version.IsLatest {
+			mark += " (当前版本)"
+		}
+		if version.IsDeleteMarker {
+			fmt.Printf("  #%d  %s  %s  已删除%s\n", version.Version, version.ModTime.Format("2006-01-02 15:04:05"), version.VersionID, mark)
+			continue
+		}
+		fmt.Printf("  #%d  %s  %s  %d 字节%s\n", version.Version, version.ModTime.Format("2006-01-02 15:04:05"), version.VersionID, version.Size, mark)
+	}
+}
+
+// cmdRestoreFileVersion 将远程文件恢复到指定版本
+func (a *App) cmdRestoreFileVersion() {
+	if len(os.Args) < 5 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync restore-version <远程路径> <版本ID>")
+		os.Exit(1)
+	}
+
+	remotePath, versionID := os.Args[3], os.Args[4]
+	if err := a.RestoreFileVersion(remotePath, versionID); err != nil {
+		fmt.Printf("恢复文件版本失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已将 %s 恢复到版本 %s\n", remotePath, versionID)
+}
+
+// cmdDownloadFileVersion 下载远程文件的指定版本
+func (a *App) cmdDownloadFileVersion() {
+	if len(os.Args) < 5 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync download-version <远程路径> <版本ID> [本地路径]")
+		os.Exit(1)
+	}
+
+	remotePath, versionID := os.Args[3], os.Args[4]
+	localPath := filepath.Base(remotePath)
+	if len(os.Args) >= 6 {
+		localPath = os.Args[5]
+	}
+
+	if err := a.DownloadFileVersion(remotePath, versionID, localPath); err != nil {
+		fmt.Printf("下载文件版本失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已将 %s 的版本 %s 下载到 %s\n", remotePath, versionID, localPath)
+}
+
+// cmdSetPreserveOwner 设置规则是否记录并还原文件所有者
+func (a *App) cmdSetPreserveOwner() {
+	if len(os.Args) < 5 || (os.Args[4] != "on" && os.Args[4] != "off") {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync preserve-owner <规则ID或名称> <on|off>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	rule.PreserveOwner = os.Args[4] == "on"
+	if err := a.UpdateSyncRule(rule); err != nil {
+		fmt.Printf("设置文件所有者还原失败: %v\n", err)
+		os.Exit(1)
+	}
+	if rule.PreserveOwner {
+		fmt.Printf("规则 '%s' 将记录并还原文件所有者\n", rule.Name)
+	} else {
+		fmt.Printf("规则 '%s' 不再记录和还原文件所有者\n", rule.Name)
+	}
+}
+
+// cmdEncryptRule 为规则启用客户端加密
+func (a *App) cmdEncryptRule() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync encrypt <规则ID或名称> [--names]")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	encryptNames := false
+	for _, arg := range os.Args[4:] {
+		if arg != "--names" {
+			fmt.Printf("错误: 未知的参数: %s\n", arg)
+			os.Exit(1)
+		}
+		encryptNames = true
+	}
+
+	if err := a.EnableRuleEncryption(rule.ID, cmdReadPassphrase(), encryptNames, true); err != nil {
+		fmt.Printf("启用加密失败: %v\n", err)
+		os.Exit(1)
+	}
+	rule, _ = a.GetSyncRuleByID(rule.ID)
+	if rule.Encryption.EncryptNames {
+		fmt.Printf("规则 '%s' 已启用加密 (加密文件名)\n", rule.Name)
+	} else {
+		fmt.Printf("规则 '%s' 已启用加密\n", rule.Name)
+	}
+	fmt.Println("请牢记密码，忘记密码将无法恢复已加密的文件")
+}
+
+// cmdUnlockRule 解锁加密规则
+func (a *App) cmdUnlockRule() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync unlock <规则ID或名称>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	if err := a.UnlockRuleEncryption(rule.ID, cmdReadPassphrase(), true); err != nil {
+		fmt.Printf("解锁失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("规则 '%s' 已解锁\n", rule.Name)
+}
+
+// cmdLockRule 锁定加密规则
+func (a *App) cmdLockRule() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync lock <规则ID或名称>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	if err := a.LockRuleEncryption(rule.ID); err != nil {
+		fmt.Printf("锁定失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("规则 '%s' 已锁定\n", rule.Name)
+}
+
+// cmdSetCompression 设置规则的上传压缩
+func (a *App) cmdSetCompression() {
+	if len(os.Args) < 5 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync compress <规则ID或名称> <zstd|gzip|off> [--ext=.log,.csv] [--probe]")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	compression := rule.Compression
+	if os.Args[4] == "off" {
+		compression.Enabled = false
+	} else {
+		compression.Enabled = true
+		compression.Algorithm = os.Args[4]
+	}
+	for _, arg := range os.Args[5:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch name {
+		case "ext":
+			compression.Extensions = nil
+			for _, ext := range strings.Split(value, ",") {
+				if ext = strings.TrimSpace(ext); ext != "" {
+					compression.Extensions = append(compression.Extensions, ext)
+				}
+			}
+		case "probe":
+			compression.Probe = true
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	rule.Compression = compression
+	if err := a.UpdateSyncRule(rule); err != nil {
+		fmt.Printf("设置上传压缩失败: %v\n", err)
+		os.Exit(1)
+	}
+	if compression.Enabled {
+		fmt.Printf("规则 '%s' 已启用上传压缩 (%s)，只影响之后上传的文件\n", rule.Name, compression.algorithm())
+	} else {
+		fmt.Printf("规则 '%s' 已关闭上传压缩\n", rule.Name)
+	}
+}
+
+// cmdSetChunking 设置规则的大文件分块存储
+func (a *App) cmdSetChunking() {
+	if len(os.Args) < 5 || (os.Args[4] != "on" && os.Args[4] != "off") {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync chunking <规则ID或名称> <on|off> [--min-size=MB]")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	chunking := rule.Chunking
+	chunking.Enabled = os.Args[4] == "on"
+	for _, arg := range os.Args[5:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch name {
+		case "min-size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				fmt.Printf("错误: 无效的最小文件大小: %s\n", value)
+				os.Exit(1)
+			}
+			chunking.MinFileSize = size * 1024 * 1024
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	rule.Chunking = chunking
+	if err := a.UpdateSyncRule(rule); err != nil {
+		fmt.Printf("设置分块存储失败: %v\n", err)
+		os.Exit(1)
+	}
+	if chunking.Enabled {
+		fmt.Printf("规则 '%s' 已启用分块存储 (不小于 %d MB 的文件)，只影响之后上传的文件\n", rule.Name, chunking.minFileSize()/(1024*1024))
+	} else {
+		fmt.Printf("规则 '%s' 已关闭分块存储，已分块存储的文件仍可正常下载\n", rule.Name)
+	}
+}
+
+// cmdPruneChunks 删除规则中不再被引用的分块
+func (a *App) cmdPruneChunks() {
+	if len(os.Args) < 4 {
+		fmt.Println("错误: 参数不足")
+		fmt.Println("用法: acloud sync prune-chunks <规则ID或名称>")
+		os.Exit(1)
+	}
+
+	rule := a.cmdFindRule(os.Args[3])
+	removed, err := a.PruneChunks(rule.ID)
+	if err != nil {
+		fmt.Printf("清理分块失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已清理 %d 个未引用的分块\n", removed)
+}
+
+// cmdServerEncryption 显示或设置全局或规则的服务端加密
+func (a *App) cmdServerEncryption() {
+	if len(os.Args) < 4 {
+		mode := a.GetServerEncryption().Mode
+		if mode == "" {
+			mode = ServerEncryptionNone
+		}
+		fmt.Printf("全局服务端加密: %s\n", mode)
+		for _, rule := range a.GetSyncRules() {
+			if rule.ServerEncryption.Mode != ServerEncryptionInherit {
+				fmt.Printf("  规则 '%s': %s\n", rule.Name, rule.ServerEncryption.Mode)
+			}
+		}
+		return
+	}
+
+	mode := os.Args[3]
+	var ruleName, customerKey string
+	for _, arg := range os.Args[4:] {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		switch name {
+		case "rule":
+			ruleName = value
+		case "key":
+			customerKey = value
+		default:
+			fmt.Printf("错误: 未知的参数: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	if ruleName == "" {
+		if err := a.SetServerEncryption(mode, customerKey); err != nil {
+			fmt.Printf("设置服务端加密失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("全局服务端加密已设置为 %s\n", mode)
+		return
+	}
+
+	rule := a.cmdFindRule(ruleName)
+	if mode == "inherit" {
+		mode = ServerEncryptionInherit
+	}
+	if err := a.SetRuleServerEncryption(rule.ID, mode, customerKey); err != nil {
+		fmt.Printf("设置服务端加密失败: %v\n", err)
+		os.Exit(1)
+	}
+	if mode == ServerEncryptionInherit {
+		fmt.Printf("规则 '%s' 使用全局服务端加密设置\n", rule.Name)
+	} else {
+		fmt.Printf("规则 '%s' 的服务端加密已设置为 %s\n", rule.Name, mode)
+	}
+}
+
+// cmdExportServerEncryptionKey 导出全局或规则的 SSE-C 密钥
+func (a *App) cmdExportServerEncryptionKey() {
+	ruleID := ""
+	if len(os.Args) >= 4 {
+		ruleID = a.cmdFindRule(os.Args[3]).ID
+	}
+
+	key, err := a.ExportServerEncryptionKey(ruleID)
+	if err != nil {
+		fmt.Printf("导出密钥失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(key)
+}
+
+// cmdReadPassphrase 读取加密密码：优先使用环境变量，否则从标准输入读取一行
+func cmdReadPassphrase() string {
+	if passphrase := os.Getenv("ACLOUD_PASSPHRASE"); passphrase != "" {
+		return passphrase
+	}
+
+	fmt.Print("请输入加密密码: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Printf("读取密码失败: %v\n", err)
+		os.Exit(1)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
 // AddSyncRule 添加同步规则
 func (a *App) AddSyncRule(rule SyncRule) {
 	a.syncRules = append(a.syncRules, rule)
@@ -382,6 +1113,11 @@ func (a *App) RemoveSyncRule(ruleID string) error {
 		if rule.ID == ruleID {
 			a.syncRules = append(a.syncRules[:i], a.syncRules[i+1:]...)
 			a.SaveSyncRules()
+
+			// 规则删除后其同步索引不再有意义
+			if err := a.removeSyncIndex(ruleID); err != nil {
+				fmt.Printf("%v\n", err)
+			}
 			return nil
 		}
 	}
@@ -437,6 +1173,10 @@ func (a *App) SaveSyncRules() error {
 		return fmt.Errorf("写入同步规则文件失败: %v", err)
 	}
 
+	// 规则变化后重新建立文件监控
+	a.restartFileWatcher()
+	a.restartRemoteWatcher()
+
 	return nil
 }
 
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩算法
+const (
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+)
+
+// compressionMetadataKey 压缩对象写入用户元数据的压缩算法，下载时据此解压
+const compressionMetadataKey = "Compression"
+
+const (
+	// compressionProbeSize 压缩率探测读取的样本大小
+	compressionProbeSize = 128 * 1024
+	// compressionProbeRatio 样本压缩后不超过原大小的该比例才压缩整个文件
+	compressionProbeRatio = 0.9
+)
+
+// defaultCompressExtensions 未配置扩展名时默认压缩的文本类文件
+var defaultCompressExtensions = []string{
+	".txt", ".log", ".csv", ".tsv", ".json", ".xml", ".md", ".html", ".css", ".sql", ".yaml", ".yml",
+	".go", ".py", ".java", ".c", ".cpp", ".h", ".js", ".ts", ".php", ".rb", ".sh",
+}
+
+// RuleCompression 同步规则的上传压缩设置
+type RuleCompression struct {
+	Enabled    bool     `json:"enabled"`
+	Algorithm  string   `json:"algorithm"`  // zstd 或 gzip，空表示 zstd
+	Extensions []string `json:"extensions"` // 压缩的文件扩展名，空表示使用默认的文本类扩展名
+	Probe      bool     `json:"probe"`      // 其他扩展名的文件先压缩样本，压缩效果明显时才压缩
+}
+
+// validate 检查压缩设置
+func (c RuleCompression) validate() error {
+	switch c.Algorithm {
+	case "", CompressionZstd, CompressionGzip:
+		return nil
+	}
+	return fmt.Errorf("不支持的压缩算法: %s", c.Algorithm)
+}
+
+// algorithm 实际使用的压缩算法
+func (c RuleCompression) algorithm() string {
+	if c.Algorithm == "" {
+		return CompressionZstd
+	}
+	return c.Algorithm
+}
+
+// matchesExtension 判断文件扩展名是否在压缩列表中
+func (c RuleCompression) matchesExtension(path string) bool {
+	extensions := c.Extensions
+	if len(extensions) == 0 {
+		extensions = defaultCompressExtensions
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, candidate := range extensions {
+		candidate = strings.ToLower(candidate)
+		if !strings.HasPrefix(candidate, ".") {
+			candidate = "." + candidate
+		}
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// compressionRuleForKey 获取对象所属的启用了压缩的规则
+func (a *App) compressionRuleForKey(key string) (SyncRule, bool) {
+	return a.ruleForKey(key, func(rule SyncRule) bool { return rule.Compression.Enabled })
+}
+
+// compressionFor 上传本地文件到对象键时使用的压缩算法，不压缩时返回空字符串
+func (a *App) compressionFor(remotePath, localPath string) (string, error) {
+	rule, ok := a.compressionRuleForKey(remotePath)
+	if !ok {
+		return "", nil
+	}
+	if rule.Compression.matchesExtension(localPath) {
+		return rule.Compression.algorithm(), nil
+	}
+	if !rule.Compression.Probe {
+		return "", nil
+	}
+
+	compressible, err := probeCompressible(localPath, rule.Compression.algorithm())
+	if err != nil || !compressible {
+		return "", err
+	}
+	return rule.Compression.algorithm(), nil
+}
+
+// probeCompressible 压缩文件开头的样本，判断文件是否值得压缩
+func probeCompressible(localPath, algorithm string) (bool, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return false, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	sample, err := io.ReadAll(io.LimitReader(file, compressionProbeSize))
+	if err != nil {
+		return false, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if len(sample) == 0 {
+		return false, nil
+	}
+
+	var compressed bytes.Buffer
+	if err := compressTo(&compressed, bytes.NewReader(sample), algorithm); err != nil {
+		return false, err
+	}
+	return float64(compressed.Len()) <= float64(len(sample))*compressionProbeRatio, nil
+}
+
+// compressTo 将内容压缩写入 w
+func compressTo(w io.Writer, r io.Reader, algorithm string) error {
+	var encoder io.WriteCloser
+	switch algorithm {
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return fmt.Errorf("创建压缩器失败: %v", err)
+		}
+		encoder = zw
+	case CompressionGzip:
+		encoder = gzip.NewWriter(w)
+	default:
+		return fmt.Errorf("不支持的压缩算法: %s", algorithm)
+	}
+
+	if _, err := io.Copy(encoder, r); err != nil {
+		encoder.Close()
+		return fmt.Errorf("压缩文件失败: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("压缩文件失败: %v", err)
+	}
+	return nil
+}
+
+// compressToTemp 将文件压缩到临时文件，返回的文件关闭后需要由调用方删除
+// 上传前需要知道压缩后的大小，因此不直接边压缩边上传
+func compressToTemp(file *os.File, algorithm string) (*os.File, int64, error) {
+	tmpFile, err := os.CreateTemp("", "acloud-compress-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	fail := func(err error) (*os.File, int64, error) {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, 0, err
+	}
+
+	if err := compressTo(tmpFile, file, algorithm); err != nil {
+		return fail(err)
+	}
+	size, err := tmpFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fail(fmt.Errorf("读取临时文件失败: %v", err))
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return fail(fmt.Errorf("读取临时文件失败: %v", err))
+	}
+	return tmpFile, size, nil
+}
+
+// decompressReader 按对象元数据记录的算法解压内容
+func decompressReader(r io.Reader, algorithm string) (io.Reader, error) {
+	switch algorithm {
+	case CompressionZstd:
+		// 单线程解码器同步运行，不会留下后台协程
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("创建解压器失败: %v", err)
+		}
+		return zr, nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("解压失败: %v", err)
+		}
+		return zr, nil
+	}
+	return nil, fmt.Errorf("不支持的压缩算法: %s", algorithm)
+}
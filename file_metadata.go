@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 上传时写入对象用户元数据的文件属性，下载时据此还原
+const (
+	mtimeMetadataKey = "Mtime" // 修改时间，Unix纳秒
+	modeMetadataKey  = "Mode"  // 权限位，八进制
+	ownerMetadataKey = "Owner" // 所有者，uid:gid，仅类Unix系统且规则启用时记录
+)
+
+// preserveOwner 判断对象所属的规则是否记录并还原文件所有者
+// uid:gid 只在同一台机器或统一账户的机器间有意义，且任何能写入存储桶的一方都能借此指定下载文件的所有者，因此默认关闭
+func (a *App) preserveOwner(remotePath string) bool {
+	_, ok := a.ruleForKey(remotePath, func(rule SyncRule) bool { return rule.PreserveOwner })
+	return ok
+}
+
+// fileMetadata 生成上传文件时写入的用户元数据：内容校验和、修改时间、权限，owner 为 true 时还记录所有者
+func fileMetadata(info os.FileInfo, checksum string, owner bool) map[string]string {
+	metadata := checksumMetadata(checksum)
+	metadata[mtimeMetadataKey] = strconv.FormatInt(info.ModTime().UnixNano(), 10)
+	metadata[modeMetadataKey] = strconv.FormatUint(uint64(info.Mode().Perm()), 8)
+	if !owner {
+		return metadata
+	}
+	if value, ok := fileOwner(info); ok {
+		metadata[ownerMetadataKey] = value
+	}
+	return metadata
+}
+
+// metadataValue 读取用户元数据
+// StatObject 返回的键不带 X-Amz-Meta- 前缀，列表接口返回的键带前缀，两种都兼容
+func metadataValue(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(strings.TrimPrefix(http.CanonicalHeaderKey(k), "X-Amz-Meta-"), key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// applyFileMetadata 按对象元数据还原本地文件的权限、修改时间，owner 为 true 时还原所有者
+// 没有记录的属性保持不变；修改时间最后设置，避免被其他操作覆盖
+func applyFileMetadata(path string, metadata map[string]string, owner bool) error {
+	if value := metadataValue(metadata, modeMetadataKey); value != "" {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err == nil {
+			if err := os.Chmod(path, os.FileMode(mode).Perm()); err != nil {
+				return fmt.Errorf("设置文件权限失败: %v", err)
+			}
+		}
+	}
+
+	if value := metadataValue(metadata, ownerMetadataKey); owner && value != "" {
+		if err := restoreFileOwner(path, value); err != nil {
+			return fmt.Errorf("设置文件所有者失败: %v", err)
+		}
+	}
+
+	if value := metadataValue(metadata, mtimeMetadataKey); value != "" {
+		nanos, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			mtime := time.Unix(0, nanos)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				return fmt.Errorf("设置文件修改时间失败: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileOwner 获取文件所有者，格式为 uid:gid
+func fileOwner(info os.FileInfo) (string, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", stat.Uid, stat.Gid), true
+}
+
+// restoreFileOwner 还原文件所有者
+// 只有root用户才能修改所有者，普通用户运行时跳过
+func restoreFileOwner(path, owner string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+
+	var uid, gid int
+	if _, err := fmt.Sscanf(owner, "%d:%d", &uid, &gid); err != nil {
+		return nil
+	}
+	return os.Lchown(path, uid, gid)
+}
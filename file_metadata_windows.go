@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// fileOwner Windows 下不记录文件所有者
+func fileOwner(info os.FileInfo) (string, bool) {
+	return "", false
+}
+
+// restoreFileOwner Windows 下不还原文件所有者
+func restoreFileOwner(path, owner string) error {
+	return nil
+}
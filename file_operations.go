@@ -40,6 +40,10 @@ func (a *App) ListFiles(dirPath string) ([]FileInfo, error) {
 	// 转换为 FileInfo 结构体
 	var files []FileInfo
 	for _, entry := range entries {
+		// 回收站只通过回收站接口访问
+		if dirPath == "" && entry.Name() == trashFolder {
+			continue
+		}
 		info, err := entry.Info()
 		if err != nil {
 			continue
@@ -80,15 +84,14 @@ func (a *App) CreateFolder(path, name string) error {
 	return os.MkdirAll(fullPath, 0755)
 }
 
-// DeleteFile 删除文件或文件夹
+// DeleteFile 删除文件或文件夹，移入当前用户的本地回收站
 func (a *App) DeleteFile(path string) error {
 	// 检查用户是否已登录
 	if !a.isLoggedIn {
 		return fmt.Errorf("用户未登录")
 	}
 	
-	fullPath := filepath.Join(a.storagePath, path)
-	return os.RemoveAll(fullPath)
+	return a.moveLocalToTrash(path)
 }
 
 // RenameFile 重命名文件或文件夹
@@ -237,6 +240,15 @@ func (a *App) GetFilePreview(path string) (*FilePreview, error) {
 		return nil, err
 	}
 	
+	preview := newFilePreview(path, mimeType, content)
+	
+	fmt.Printf("文件预览: 路径=%s, 类型=%s, 是否Base64=%v\n", path, mimeType, preview.IsBase64)
+	
+	return preview, nil
+}
+
+// newFilePreview 根据文件名和类型生成预览，文本和代码文件返回原文，其他文件返回Base64编码
+func newFilePreview(path, mimeType string, content []byte) *FilePreview {
 	// 判断是否为文本文件
 	isText := strings.HasPrefix(mimeType, "text/") || 
 		mimeType == "application/json" || 
@@ -271,9 +283,28 @@ func (a *App) GetFilePreview(path string) (*FilePreview, error) {
 		preview.Content = base64.StdEncoding.EncodeToString(content)
 	}
 	
-	fmt.Printf("文件预览: 路径=%s, 类型=%s, 是否Base64=%v\n", path, mimeType, preview.IsBase64)
-	
-	return preview, nil
+	return preview
+}
+
+// GetMinioFilePreview 获取远程文件的预览信息，压缩和加密的对象自动还原
+func (a *App) GetMinioFilePreview(remotePath string) (*FilePreview, error) {
+	if !a.isLoggedIn {
+		return nil, fmt.Errorf("用户未登录")
+	}
+	if !a.minioConfig.Enabled || a.minioClient == nil {
+		return nil, fmt.Errorf("MinIO 未启用")
+	}
+
+	content, err := a.DownloadFileFromMinio(remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(remotePath))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+	return newFilePreview(remotePath, mimeType, content), nil
 }
 
 // OpenInExplorer 在系统资源管理器中打开指定路径
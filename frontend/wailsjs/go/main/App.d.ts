@@ -15,6 +15,8 @@ export function CreateFolder(arg1:string,arg2:string):Promise<void>;
 
 export function CreateMinioFolder(arg1:string):Promise<void>;
 
+export function CreateShareLink(arg1:string,arg2:number,arg3:string,arg4:number):Promise<main.ShareLink>;
+
 export function CreateSyncReport(arg1:main.SyncStatus):Promise<string>;
 
 export function DeleteFile(arg1:string):Promise<void>;
@@ -29,16 +31,30 @@ export function DownloadFile(arg1:string):Promise<Array<number>>;
 
 export function DownloadFileFromMinio(arg1:string):Promise<Array<number>>;
 
+export function DownloadFileVersion(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function EmptyTrash():Promise<void>;
+
+export function EnableRuleEncryption(arg1:string,arg2:string,arg3:boolean,arg4:boolean):Promise<void>;
+
 export function EnableSyncRule(arg1:string):Promise<void>;
 
+export function ExportServerEncryptionKey(arg1:string):Promise<string>;
+
 export function ExportSyncConfig(arg1:string):Promise<void>;
 
 export function GetAppVersion():Promise<string>;
 
+export function GetBandwidthConfig():Promise<main.BandwidthConfig>;
+
+export function GetBucketVersioning():Promise<string>;
+
 export function GetConflictCount():Promise<number>;
 
 export function GetConflictFiles():Promise<Array<main.ConflictFile>>;
 
+export function GetCurrentBandwidthLimits():Promise<main.BandwidthLimits>;
+
 export function GetCurrentUser():Promise<string>;
 
 export function GetEnabledSyncRules():Promise<Array<main.SyncRule>>;
@@ -47,10 +63,18 @@ export function GetFilePreview(arg1:string):Promise<main.FilePreview>;
 
 export function GetFileType(arg1:string):Promise<string>;
 
+export function GetFileWatcherStatus():Promise<main.FileWatcherStatus>;
+
 export function GetMinioConfig():Promise<main.MinioConfig>;
 
 export function GetMinioFileInfo(arg1:string):Promise<main.MinioFileInfo>;
 
+export function GetMinioFilePreview(arg1:string):Promise<main.FilePreview>;
+
+export function GetServerEncryption():Promise<main.ServerEncryption>;
+
+export function GetShareConfig():Promise<main.ShareConfig>;
+
 export function GetStoragePath():Promise<string>;
 
 export function GetSyncHistory():Promise<Array<main.SyncHistoryEntry>>;
@@ -79,8 +103,12 @@ export function GetSyncStatus():Promise<main.SyncStatus>;
 
 export function GetSystemInfo():Promise<Record<string, any>>;
 
+export function GetTrashConfig():Promise<main.TrashConfig>;
+
 export function GetTrayMenuItems():Promise<Array<main.TrayMenuItem>>;
 
+export function GetVerifyReport(arg1:string):Promise<main.VerifyReport>;
+
 export function Greet(arg1:string):Promise<string>;
 
 export function HandleSyncError(arg1:Error,arg2:string,arg3:string):Promise<void>;
@@ -97,6 +125,14 @@ export function IsLoggedIn():Promise<boolean>;
 
 export function IsRemoteFileNewer(arg1:string,arg2:string):Promise<boolean>;
 
+export function IsRuleEncryptionUnlocked(arg1:string):Promise<boolean>;
+
+export function IsSyncPaused():Promise<boolean>;
+
+export function ListBackupSnapshots(arg1:string):Promise<Array<main.BackupSnapshot>>;
+
+export function ListFileVersions(arg1:string):Promise<Array<main.FileVersion>>;
+
 export function ListFiles(arg1:string):Promise<Array<main.FileInfo>>;
 
 export function ListMinioBuckets():Promise<Array<string>>;
@@ -105,8 +141,14 @@ export function ListMinioFiles(arg1:string):Promise<Array<main.MinioFileInfo>>;
 
 export function ListMinioFilesByBucket(arg1:string,arg2:string):Promise<Array<main.MinioFileInfo>>;
 
+export function ListShareLinks():Promise<Array<main.ShareLink>>;
+
+export function ListTrash():Promise<Array<main.TrashItem>>;
+
 export function LoadSyncRules():Promise<void>;
 
+export function LockRuleEncryption(arg1:string):Promise<void>;
+
 export function LogSyncEvent(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function Login(arg1:string,arg2:string):Promise<main.AuthResponse>;
@@ -123,6 +165,14 @@ export function OpenInExplorer(arg1:string):Promise<void>;
 
 export function OptimizeSyncPerformance():Promise<void>;
 
+export function PauseSync():Promise<void>;
+
+export function PreviewSyncPlan(arg1:string):Promise<main.SyncPlan>;
+
+export function PruneBackupSnapshots(arg1:string):Promise<number>;
+
+export function PruneChunks(arg1:string):Promise<number>;
+
 export function ReadFile(arg1:string):Promise<Array<number>>;
 
 export function ReadSyncReport(arg1:string):Promise<string>;
@@ -141,6 +191,16 @@ export function ResolveAllConflicts(arg1:string):Promise<void>;
 
 export function ResolveConflict(arg1:string,arg2:string):Promise<void>;
 
+export function RestoreFileVersion(arg1:string,arg2:string):Promise<void>;
+
+export function RestoreSnapshot(arg1:string,arg2:string,arg3:main.RestoreOptions):Promise<main.RestoreResult>;
+
+export function RestoreTrashItem(arg1:string,arg2:string):Promise<void>;
+
+export function ResumeSync():Promise<void>;
+
+export function RevokeShareLink(arg1:string):Promise<void>;
+
 export function RunSyncCommand():Promise<void>;
 
 export function SaveConfig():Promise<void>;
@@ -157,10 +217,28 @@ export function SendSyncNotification(arg1:string,arg2:string):Promise<void>;
 
 export function SetAutoStart(arg1:boolean):Promise<void>;
 
+export function SetBackupRetention(arg1:string,arg2:main.BackupRetention):Promise<void>;
+
+export function SetBucketVersioning(arg1:boolean):Promise<void>;
+
+export function SetFileWatcherEnabled(arg1:boolean):Promise<void>;
+
+export function SetMaxSyncConcurrency(arg1:number):Promise<void>;
+
+export function SetRuleServerEncryption(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SetScrubInterval(arg1:number):Promise<void>;
+
+export function SetServerEncryption(arg1:string,arg2:string):Promise<void>;
+
 export function SetSyncInterval(arg1:number):Promise<void>;
 
 export function SetSyncMode(arg1:string):Promise<void>;
 
+export function SetTransferVerification(arg1:boolean):Promise<void>;
+
+export function SetTrashRetentionDays(arg1:number):Promise<void>;
+
 export function ShowFromTray():Promise<void>;
 
 export function StartSync():Promise<void>;
@@ -173,8 +251,14 @@ export function ToggleSyncStatus():Promise<boolean>;
 
 export function TriggerManualSync():Promise<void>;
 
+export function UnlockRuleEncryption(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
+export function UpdateBandwidthConfig(arg1:main.BandwidthConfig):Promise<void>;
+
 export function UpdateMinioConfig(arg1:string,arg2:string,arg3:string,arg4:string,arg5:boolean,arg6:boolean):Promise<void>;
 
+export function UpdateShareConfig(arg1:main.ShareConfig):Promise<void>;
+
 export function UpdateSyncConfig(arg1:boolean,arg2:number,arg3:string,arg4:string):Promise<void>;
 
 export function UpdateSyncProgress(arg1:main.SyncProgress):Promise<void>;
@@ -190,3 +274,5 @@ export function UploadFileString(arg1:string,arg2:string,arg3:string):Promise<vo
 export function UploadFileToMinio(arg1:string,arg2:string):Promise<void>;
 
 export function ValidateSyncRule(arg1:main.SyncRule):Promise<void>;
+
+export function VerifySyncRule(arg1:string,arg2:string,arg3:boolean):Promise<main.VerifyReport>;
@@ -26,6 +26,10 @@ export function CreateMinioFolder(arg1) {
   return window['go']['main']['App']['CreateMinioFolder'](arg1);
 }
 
+export function CreateShareLink(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreateShareLink'](arg1, arg2, arg3, arg4);
+}
+
 export function CreateSyncReport(arg1) {
   return window['go']['main']['App']['CreateSyncReport'](arg1);
 }
@@ -54,10 +58,26 @@ export function DownloadFileFromMinio(arg1) {
   return window['go']['main']['App']['DownloadFileFromMinio'](arg1);
 }
 
+export function DownloadFileVersion(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DownloadFileVersion'](arg1, arg2, arg3);
+}
+
+export function EmptyTrash() {
+  return window['go']['main']['App']['EmptyTrash']();
+}
+
+export function EnableRuleEncryption(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['EnableRuleEncryption'](arg1, arg2, arg3, arg4);
+}
+
 export function EnableSyncRule(arg1) {
   return window['go']['main']['App']['EnableSyncRule'](arg1);
 }
 
+export function ExportServerEncryptionKey(arg1) {
+  return window['go']['main']['App']['ExportServerEncryptionKey'](arg1);
+}
+
 export function ExportSyncConfig(arg1) {
   return window['go']['main']['App']['ExportSyncConfig'](arg1);
 }
@@ -66,6 +86,14 @@ export function GetAppVersion() {
   return window['go']['main']['App']['GetAppVersion']();
 }
 
+export function GetBandwidthConfig() {
+  return window['go']['main']['App']['GetBandwidthConfig']();
+}
+
+export function GetBucketVersioning() {
+  return window['go']['main']['App']['GetBucketVersioning']();
+}
+
 export function GetConflictCount() {
   return window['go']['main']['App']['GetConflictCount']();
 }
@@ -74,6 +102,10 @@ export function GetConflictFiles() {
   return window['go']['main']['App']['GetConflictFiles']();
 }
 
+export function GetCurrentBandwidthLimits() {
+  return window['go']['main']['App']['GetCurrentBandwidthLimits']();
+}
+
 export function GetCurrentUser() {
   return window['go']['main']['App']['GetCurrentUser']();
 }
@@ -90,6 +122,10 @@ export function GetFileType(arg1) {
   return window['go']['main']['App']['GetFileType'](arg1);
 }
 
+export function GetFileWatcherStatus() {
+  return window['go']['main']['App']['GetFileWatcherStatus']();
+}
+
 export function GetMinioConfig() {
   return window['go']['main']['App']['GetMinioConfig']();
 }
@@ -98,6 +134,18 @@ export function GetMinioFileInfo(arg1) {
   return window['go']['main']['App']['GetMinioFileInfo'](arg1);
 }
 
+export function GetMinioFilePreview(arg1) {
+  return window['go']['main']['App']['GetMinioFilePreview'](arg1);
+}
+
+export function GetServerEncryption() {
+  return window['go']['main']['App']['GetServerEncryption']();
+}
+
+export function GetShareConfig() {
+  return window['go']['main']['App']['GetShareConfig']();
+}
+
 export function GetStoragePath() {
   return window['go']['main']['App']['GetStoragePath']();
 }
@@ -154,10 +202,18 @@ export function GetSystemInfo() {
   return window['go']['main']['App']['GetSystemInfo']();
 }
 
+export function GetTrashConfig() {
+  return window['go']['main']['App']['GetTrashConfig']();
+}
+
 export function GetTrayMenuItems() {
   return window['go']['main']['App']['GetTrayMenuItems']();
 }
 
+export function GetVerifyReport(arg1) {
+  return window['go']['main']['App']['GetVerifyReport'](arg1);
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
@@ -190,6 +246,22 @@ export function IsRemoteFileNewer(arg1, arg2) {
   return window['go']['main']['App']['IsRemoteFileNewer'](arg1, arg2);
 }
 
+export function IsRuleEncryptionUnlocked(arg1) {
+  return window['go']['main']['App']['IsRuleEncryptionUnlocked'](arg1);
+}
+
+export function IsSyncPaused() {
+  return window['go']['main']['App']['IsSyncPaused']();
+}
+
+export function ListBackupSnapshots(arg1) {
+  return window['go']['main']['App']['ListBackupSnapshots'](arg1);
+}
+
+export function ListFileVersions(arg1) {
+  return window['go']['main']['App']['ListFileVersions'](arg1);
+}
+
 export function ListFiles(arg1) {
   return window['go']['main']['App']['ListFiles'](arg1);
 }
@@ -206,10 +278,22 @@ export function ListMinioFilesByBucket(arg1, arg2) {
   return window['go']['main']['App']['ListMinioFilesByBucket'](arg1, arg2);
 }
 
+export function ListShareLinks() {
+  return window['go']['main']['App']['ListShareLinks']();
+}
+
+export function ListTrash() {
+  return window['go']['main']['App']['ListTrash']();
+}
+
 export function LoadSyncRules() {
   return window['go']['main']['App']['LoadSyncRules']();
 }
 
+export function LockRuleEncryption(arg1) {
+  return window['go']['main']['App']['LockRuleEncryption'](arg1);
+}
+
 export function LogSyncEvent(arg1, arg2, arg3) {
   return window['go']['main']['App']['LogSyncEvent'](arg1, arg2, arg3);
 }
@@ -242,6 +326,22 @@ export function OptimizeSyncPerformance() {
   return window['go']['main']['App']['OptimizeSyncPerformance']();
 }
 
+export function PauseSync() {
+  return window['go']['main']['App']['PauseSync']();
+}
+
+export function PreviewSyncPlan(arg1) {
+  return window['go']['main']['App']['PreviewSyncPlan'](arg1);
+}
+
+export function PruneBackupSnapshots(arg1) {
+  return window['go']['main']['App']['PruneBackupSnapshots'](arg1);
+}
+
+export function PruneChunks(arg1) {
+  return window['go']['main']['App']['PruneChunks'](arg1);
+}
+
 export function ReadFile(arg1) {
   return window['go']['main']['App']['ReadFile'](arg1);
 }
@@ -278,6 +378,26 @@ export function ResolveConflict(arg1, arg2) {
   return window['go']['main']['App']['ResolveConflict'](arg1, arg2);
 }
 
+export function RestoreFileVersion(arg1, arg2) {
+  return window['go']['main']['App']['RestoreFileVersion'](arg1, arg2);
+}
+
+export function RestoreSnapshot(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RestoreSnapshot'](arg1, arg2, arg3);
+}
+
+export function RestoreTrashItem(arg1, arg2) {
+  return window['go']['main']['App']['RestoreTrashItem'](arg1, arg2);
+}
+
+export function ResumeSync() {
+  return window['go']['main']['App']['ResumeSync']();
+}
+
+export function RevokeShareLink(arg1) {
+  return window['go']['main']['App']['RevokeShareLink'](arg1);
+}
+
 export function RunSyncCommand() {
   return window['go']['main']['App']['RunSyncCommand']();
 }
@@ -310,6 +430,34 @@ export function SetAutoStart(arg1) {
   return window['go']['main']['App']['SetAutoStart'](arg1);
 }
 
+export function SetBackupRetention(arg1, arg2) {
+  return window['go']['main']['App']['SetBackupRetention'](arg1, arg2);
+}
+
+export function SetBucketVersioning(arg1) {
+  return window['go']['main']['App']['SetBucketVersioning'](arg1);
+}
+
+export function SetFileWatcherEnabled(arg1) {
+  return window['go']['main']['App']['SetFileWatcherEnabled'](arg1);
+}
+
+export function SetMaxSyncConcurrency(arg1) {
+  return window['go']['main']['App']['SetMaxSyncConcurrency'](arg1);
+}
+
+export function SetRuleServerEncryption(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetRuleServerEncryption'](arg1, arg2, arg3);
+}
+
+export function SetScrubInterval(arg1) {
+  return window['go']['main']['App']['SetScrubInterval'](arg1);
+}
+
+export function SetServerEncryption(arg1, arg2) {
+  return window['go']['main']['App']['SetServerEncryption'](arg1, arg2);
+}
+
 export function SetSyncInterval(arg1) {
   return window['go']['main']['App']['SetSyncInterval'](arg1);
 }
@@ -318,6 +466,14 @@ export function SetSyncMode(arg1) {
   return window['go']['main']['App']['SetSyncMode'](arg1);
 }
 
+export function SetTransferVerification(arg1) {
+  return window['go']['main']['App']['SetTransferVerification'](arg1);
+}
+
+export function SetTrashRetentionDays(arg1) {
+  return window['go']['main']['App']['SetTrashRetentionDays'](arg1);
+}
+
 export function ShowFromTray() {
   return window['go']['main']['App']['ShowFromTray']();
 }
@@ -342,10 +498,22 @@ export function TriggerManualSync() {
   return window['go']['main']['App']['TriggerManualSync']();
 }
 
+export function UnlockRuleEncryption(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UnlockRuleEncryption'](arg1, arg2, arg3);
+}
+
+export function UpdateBandwidthConfig(arg1) {
+  return window['go']['main']['App']['UpdateBandwidthConfig'](arg1);
+}
+
 export function UpdateMinioConfig(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['UpdateMinioConfig'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
+export function UpdateShareConfig(arg1) {
+  return window['go']['main']['App']['UpdateShareConfig'](arg1);
+}
+
 export function UpdateSyncConfig(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['UpdateSyncConfig'](arg1, arg2, arg3, arg4);
 }
@@ -377,3 +545,7 @@ export function UploadFileToMinio(arg1, arg2) {
 export function ValidateSyncRule(arg1) {
   return window['go']['main']['App']['ValidateSyncRule'](arg1);
 }
+
+export function VerifySyncRule(arg1, arg2, arg3) {
+  return window['go']['main']['App']['VerifySyncRule'](arg1, arg2, arg3);
+}
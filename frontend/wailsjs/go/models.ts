@@ -14,6 +14,138 @@ export namespace main {
 	        this.message = source["message"];
 	    }
 	}
+	export class BackupRetention {
+	    keepLast: number;
+	    keepDaily: number;
+	    keepWeekly: number;
+	    keepMonthly: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupRetention(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keepLast = source["keepLast"];
+	        this.keepDaily = source["keepDaily"];
+	        this.keepWeekly = source["keepWeekly"];
+	        this.keepMonthly = source["keepMonthly"];
+	    }
+	}
+	export class BackupSnapshot {
+	    name: string;
+	    path: string;
+	    // Go type: time
+	    createdAt: any;
+	    size: number;
+	    fileCount: number;
+	    deduplicated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupSnapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.size = source["size"];
+	        this.fileCount = source["fileCount"];
+	        this.deduplicated = source["deduplicated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BandwidthSchedule {
+	    name: string;
+	    days: number[];
+	    start: string;
+	    end: string;
+	    uploadLimit: number;
+	    downloadLimit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BandwidthSchedule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.days = source["days"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.uploadLimit = source["uploadLimit"];
+	        this.downloadLimit = source["downloadLimit"];
+	    }
+	}
+	export class BandwidthConfig {
+	    uploadLimit: number;
+	    downloadLimit: number;
+	    schedules: BandwidthSchedule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BandwidthConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uploadLimit = source["uploadLimit"];
+	        this.downloadLimit = source["downloadLimit"];
+	        this.schedules = this.convertValues(source["schedules"], BandwidthSchedule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BandwidthLimits {
+	    uploadLimit: number;
+	    downloadLimit: number;
+	    schedule: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BandwidthLimits(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uploadLimit = source["uploadLimit"];
+	        this.downloadLimit = source["downloadLimit"];
+	        this.schedule = source["schedule"];
+	    }
+	}
+	
 	export class ConflictFile {
 	    path: string;
 	    // Go type: time
@@ -107,6 +239,91 @@ export namespace main {
 	        this.isBase64 = source["isBase64"];
 	    }
 	}
+	export class FileVersion {
+	    path: string;
+	    version: number;
+	    versionId: string;
+	    size: number;
+	    // Go type: time
+	    modTime: any;
+	    checksum: string;
+	    createdBy: string;
+	    isLatest: boolean;
+	    isDeleteMarker: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileVersion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.version = source["version"];
+	        this.versionId = source["versionId"];
+	        this.size = source["size"];
+	        this.modTime = this.convertValues(source["modTime"], null);
+	        this.checksum = source["checksum"];
+	        this.createdBy = source["createdBy"];
+	        this.isLatest = source["isLatest"];
+	        this.isDeleteMarker = source["isDeleteMarker"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileWatcherStatus {
+	    enabled: boolean;
+	    running: boolean;
+	    watchedDirs: number;
+	    watchedRules: string[];
+	    scanOnlyRules: string[];
+	    pendingFiles: number;
+	    remoteNotifyRules: string[];
+	    remotePollRules: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FileWatcherStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.running = source["running"];
+	        this.watchedDirs = source["watchedDirs"];
+	        this.watchedRules = source["watchedRules"];
+	        this.scanOnlyRules = source["scanOnlyRules"];
+	        this.pendingFiles = source["pendingFiles"];
+	        this.remoteNotifyRules = source["remoteNotifyRules"];
+	        this.remotePollRules = source["remotePollRules"];
+	    }
+	}
+	export class ServerEncryption {
+	    mode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerEncryption(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	    }
+	}
 	export class MinioConfig {
 	    endpoint: string;
 	    accessKeyID: string;
@@ -114,6 +331,7 @@ export namespace main {
 	    useSSL: boolean;
 	    bucketName: string;
 	    enabled: boolean;
+	    encryption: ServerEncryption;
 	
 	    static createFrom(source: any = {}) {
 	        return new MinioConfig(source);
@@ -127,7 +345,26 @@ export namespace main {
 	        this.useSSL = source["useSSL"];
 	        this.bucketName = source["bucketName"];
 	        this.enabled = source["enabled"];
+	        this.encryption = this.convertValues(source["encryption"], ServerEncryption);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class MinioFileInfo {
 	    name: string;
@@ -136,6 +373,9 @@ export namespace main {
 	    // Go type: time
 	    lastModified: any;
 	    isDir: boolean;
+	    etag: string;
+	    versionId: string;
+	    sha256: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new MinioFileInfo(source);
@@ -148,6 +388,158 @@ export namespace main {
 	        this.size = source["size"];
 	        this.lastModified = this.convertValues(source["lastModified"], null);
 	        this.isDir = source["isDir"];
+	        this.etag = source["etag"];
+	        this.versionId = source["versionId"];
+	        this.sha256 = source["sha256"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RestoreOptions {
+	    path: string;
+	    target: string;
+	    force: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RestoreOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.target = source["target"];
+	        this.force = source["force"];
+	    }
+	}
+	export class RestoreResult {
+	    snapshot: string;
+	    target: string;
+	    restored: number;
+	    unchanged: number;
+	    skipped: string[];
+	    restoreBytes: number;
+	    errors: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RestoreResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.snapshot = source["snapshot"];
+	        this.target = source["target"];
+	        this.restored = source["restored"];
+	        this.unchanged = source["unchanged"];
+	        this.skipped = source["skipped"];
+	        this.restoreBytes = source["restoreBytes"];
+	        this.errors = source["errors"];
+	    }
+	}
+	export class RuleChunking {
+	    enabled: boolean;
+	    minFileSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleChunking(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.minFileSize = source["minFileSize"];
+	    }
+	}
+	export class RuleCompression {
+	    enabled: boolean;
+	    algorithm: string;
+	    extensions: string[];
+	    probe: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleCompression(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.algorithm = source["algorithm"];
+	        this.extensions = source["extensions"];
+	        this.probe = source["probe"];
+	    }
+	}
+	export class RuleEncryption {
+	    enabled: boolean;
+	    encryptNames: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RuleEncryption(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.encryptNames = source["encryptNames"];
+	    }
+	}
+	
+	export class ShareConfig {
+	    address: string;
+	    baseUrl: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShareConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.baseUrl = source["baseUrl"];
+	    }
+	}
+	export class ShareLink {
+	    id: string;
+	    path: string;
+	    url: string;
+	    // Go type: time
+	    expiresAt: any;
+	    password: string;
+	    views: number;
+	    maxViews: number;
+	    // Go type: time
+	    createdAt: any;
+	    createdBy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShareLink(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.path = source["path"];
+	        this.url = source["url"];
+	        this.expiresAt = this.convertValues(source["expiresAt"], null);
+	        this.password = source["password"];
+	        this.views = source["views"];
+	        this.maxViews = source["maxViews"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.createdBy = source["createdBy"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -252,12 +644,90 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class SyncPlanItem {
+	    action: string;
+	    path: string;
+	    newPath: string;
+	    size: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncPlanItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.path = source["path"];
+	        this.newPath = source["newPath"];
+	        this.size = source["size"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class SyncPlan {
+	    ruleId: string;
+	    ruleName: string;
+	    direction: string;
+	    mode: string;
+	    items: SyncPlanItem[];
+	    uploads: number;
+	    downloads: number;
+	    deletes: number;
+	    renames: number;
+	    conflicts: number;
+	    uploadBytes: number;
+	    downloadBytes: number;
+	    deletionBlocked: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ruleId = source["ruleId"];
+	        this.ruleName = source["ruleName"];
+	        this.direction = source["direction"];
+	        this.mode = source["mode"];
+	        this.items = this.convertValues(source["items"], SyncPlanItem);
+	        this.uploads = source["uploads"];
+	        this.downloads = source["downloads"];
+	        this.deletes = source["deletes"];
+	        this.renames = source["renames"];
+	        this.conflicts = source["conflicts"];
+	        this.uploadBytes = source["uploadBytes"];
+	        this.downloadBytes = source["downloadBytes"];
+	        this.deletionBlocked = source["deletionBlocked"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
 	export class SyncProgress {
 	    totalFiles: number;
 	    processedFiles: number;
 	    uploadedFiles: number;
 	    downloadedFiles: number;
 	    currentFile: string;
+	    currentFileBytes: number;
+	    currentFileSize: number;
+	    transferredBytes: number;
 	    progress: number;
 	    status: string;
 	    error: string;
@@ -273,6 +743,9 @@ export namespace main {
 	        this.uploadedFiles = source["uploadedFiles"];
 	        this.downloadedFiles = source["downloadedFiles"];
 	        this.currentFile = source["currentFile"];
+	        this.currentFileBytes = source["currentFileBytes"];
+	        this.currentFileSize = source["currentFileSize"];
+	        this.transferredBytes = source["transferredBytes"];
 	        this.progress = source["progress"];
 	        this.status = source["status"];
 	        this.error = source["error"];
@@ -286,6 +759,14 @@ export namespace main {
 	    direction: string;
 	    filters: string[];
 	    enabled: boolean;
+	    maxDeletePercent: number;
+	    concurrency: number;
+	    retention: BackupRetention;
+	    encryption: RuleEncryption;
+	    serverEncryption: ServerEncryption;
+	    compression: RuleCompression;
+	    chunking: RuleChunking;
+	    preserveOwner: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new SyncRule(source);
@@ -300,7 +781,33 @@ export namespace main {
 	        this.direction = source["direction"];
 	        this.filters = source["filters"];
 	        this.enabled = source["enabled"];
+	        this.maxDeletePercent = source["maxDeletePercent"];
+	        this.concurrency = source["concurrency"];
+	        this.retention = this.convertValues(source["retention"], BackupRetention);
+	        this.encryption = this.convertValues(source["encryption"], RuleEncryption);
+	        this.serverEncryption = this.convertValues(source["serverEncryption"], ServerEncryption);
+	        this.compression = this.convertValues(source["compression"], RuleCompression);
+	        this.chunking = this.convertValues(source["chunking"], RuleChunking);
+	        this.preserveOwner = source["preserveOwner"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class SyncStatus {
 	    running: boolean;
@@ -345,6 +852,61 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class TrashConfig {
+	    retentionDays: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.retentionDays = source["retentionDays"];
+	    }
+	}
+	export class TrashItem {
+	    id: string;
+	    location: string;
+	    originalPath: string;
+	    // Go type: time
+	    deletedAt: any;
+	    deletedBy: string;
+	    size: number;
+	    isDir: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.location = source["location"];
+	        this.originalPath = source["originalPath"];
+	        this.deletedAt = this.convertValues(source["deletedAt"], null);
+	        this.deletedBy = source["deletedBy"];
+	        this.size = source["size"];
+	        this.isDir = source["isDir"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class TrayMenuItem {
 	    id: string;
 	    label: string;
@@ -365,6 +927,76 @@ export namespace main {
 	        this.disabled = source["disabled"];
 	    }
 	}
+	export class VerifyIssue {
+	    path: string;
+	    status: string;
+	    detail: string;
+	    repaired: boolean;
+	    repairError: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VerifyIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.status = source["status"];
+	        this.detail = source["detail"];
+	        this.repaired = source["repaired"];
+	        this.repairError = source["repairError"];
+	    }
+	}
+	export class VerifyReport {
+	    ruleId: string;
+	    ruleName: string;
+	    repair: string;
+	    deep: boolean;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    finishedAt: any;
+	    checked: number;
+	    ok: number;
+	    repaired: number;
+	    issues: VerifyIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new VerifyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ruleId = source["ruleId"];
+	        this.ruleName = source["ruleName"];
+	        this.repair = source["repair"];
+	        this.deep = source["deep"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.checked = source["checked"];
+	        this.ok = source["ok"];
+	        this.repaired = source["repaired"];
+	        this.issues = this.convertValues(source["issues"], VerifyIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
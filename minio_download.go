@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// tempDownloadSuffix 下载临时文件的后缀，同步扫描本地文件时会跳过这类文件
+const tempDownloadSuffix = ".acloud-tmp"
+
+// isTempDownloadFile 判断是否为下载过程中产生的临时文件
+func isTempDownloadFile(path string) bool {
+	return strings.HasSuffix(path, tempDownloadSuffix)
+}
+
+// objectContent 返回对象的原始内容：加密对象校验密钥后解密，压缩对象解压，其他对象原样返回
+// 第二个返回值为解密使用的加密器，未加密时为nil
+func (a *App) objectContent(remotePath string, objInfo minio.ObjectInfo, r io.Reader) (io.Reader, *ruleCipher, error) {
+	var enc *ruleCipher
+	if metadataValue(objInfo.UserMetadata, encryptionMetadataKey) != "" {
+		var err error
+		enc, err = a.cipherForKey(remotePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if enc == nil {
+			return nil, nil, fmt.Errorf("对象已加密，不属于任何加密规则: %s", remotePath)
+		}
+		if metadataValue(objInfo.UserMetadata, keyCheckMetadataKey) != enc.keyCheck {
+			return nil, nil, fmt.Errorf("对象使用了不同的密钥加密，请检查密码: %s", remotePath)
+		}
+		r = enc.decryptReader(r)
+	}
+
+	if algorithm := metadataValue(objInfo.UserMetadata, compressionMetadataKey); algorithm != "" {
+		decompressed, err := decompressReader(r, algorithm)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = decompressed
+	}
+	return r, enc, nil
+}
+
+// downloadFileToPath 以流式方式将对象下载到本地文件，返回文件内容的MD5
+// 数据先写入目标文件同目录下的临时文件，落盘后再重命名覆盖目标文件，
+// 下载中断或失败时原文件保持不变
+func (a *App) downloadFileToPath(ctx context.Context, remotePath, localPath string) (string, error) {
+	return a.downloadVersionToPath(ctx, remotePath, "", localPath)
+}
+
+// downloadVersionToPath 下载对象的指定版本，versionID 为空时下载最新版本
+func (a *App) downloadVersionToPath(ctx context.Context, remotePath, versionID, localPath string) (string, error) {
+	if a.minioClient == nil {
+		return "", fmt.Errorf("MinIO客户端未初始化")
+	}
+
+	// 确保本地目录存在
+	localDir := filepath.Dir(localPath)
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return "", fmt.Errorf("创建本地目录失败: %v", err)
+	}
+
+	// 获取对象；加密对象边下载边解密，分块存储的对象只下载本地文件中没有的分块
+	content, objInfo, enc, err := a.openObject(ctx, remotePath, versionID, localPath)
+	if err != nil {
+		return "", fmt.Errorf("获取对象失败: %v", err)
+	}
+	defer content.Close()
+
+	// 在同一目录创建临时文件，保证重命名是原子操作
+	tmpFile, err := os.CreateTemp(localDir, "."+filepath.Base(localPath)+".*"+tempDownloadSuffix)
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmpFile.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	progress := a.newTransferProgress(remotePath, objectPlainSize(objInfo))
+
+	// 边下载边计算MD5，需要校验时同时计算SHA-256
+	hash := md5.New()
+	shaHash := sha256.New()
+	writers := []io.Writer{tmpFile, hash}
+	if a.verifyTransfers {
+		writers = append(writers, shaHash)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), progress.wrap(content)); err != nil {
+		return "", fmt.Errorf("下载文件失败: %v", err)
+	}
+
+	// 校验失败时不替换本地文件
+	if a.verifyTransfers {
+		if err := verifyDownloaded(objInfo, enc, hex.EncodeToString(hash.Sum(nil)), hex.EncodeToString(shaHash.Sum(nil))); err != nil {
+			return "", err
+		}
+	}
+
+	// 确保数据写入磁盘
+	if err := tmpFile.Sync(); err != nil {
+		return "", fmt.Errorf("写入本地文件失败: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("写入本地文件失败: %v", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return "", fmt.Errorf("设置文件权限失败: %v", err)
+	}
+
+	// 还原上传时记录的修改时间和权限，避免下载后的文件被误判为本地修改
+	if err := applyFileMetadata(tmpPath, objInfo.UserMetadata, a.preserveOwner(remotePath)); err != nil {
+		return "", err
+	}
+
+	// 替换目标文件
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		return "", fmt.Errorf("替换本地文件失败: %v", err)
+	}
+	committed = true
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
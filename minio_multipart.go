@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// resumableUploadThreshold 超过该大小的文件使用可断点续传的分片上传
+	resumableUploadThreshold = 64 * 1024 * 1024
+	// resumablePartSize 分片大小
+	resumablePartSize = 16 * 1024 * 1024
+)
+
+// UploadedPart 已完成上传的分片
+type UploadedPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// UploadSession 分片上传会话，持久化保存以便中断后继续上传
+type UploadSession struct {
+	UploadID   string         `json:"uploadId"`
+	Bucket     string         `json:"bucket"`
+	RemotePath string         `json:"remotePath"`
+	LocalPath  string         `json:"localPath"`
+	Size       int64          `json:"size"`
+	ModTime    time.Time      `json:"modTime"`
+	PartSize   int64          `json:"partSize"`
+	Parts      []UploadedPart `json:"parts"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// completedBytes 已完成上传的字节数
+func (s *UploadSession) completedBytes() int64 {
+	var total int64
+	for _, part := range s.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+// hasPart 检查分片是否已上传
+func (s *UploadSession) hasPart(partNumber int) bool {
+	for _, part := range s.Parts {
+		if part.PartNumber == partNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 检查会话是否对应当前文件内容（大小和修改时间都未变化）
+func (s *UploadSession) matches(fileInfo os.FileInfo) bool {
+	return s.Size == fileInfo.Size() && s.ModTime.Equal(fileInfo.ModTime()) && s.PartSize > 0
+}
+
+// uploadSessionPath 获取分片上传会话文件路径
+func (a *App) uploadSessionPath(bucket, remotePath, localPath string) string {
+	key := fmt.Sprintf("%x", md5.Sum([]byte(bucket+"|"+remotePath+"|"+localPath)))
+	return filepath.Join(a.configDir, "uploads", key+".json")
+}
+
+// loadUploadSession 加载分片上传会话，不存在时返回nil
+func (a *App) loadUploadSession(bucket, remotePath, localPath string) (*UploadSession, error) {
+	data, err := os.ReadFile(a.uploadSessionPath(bucket, remotePath, localPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取上传会话失败: %v", err)
+	}
+
+	var session UploadSession
+	if err := a.jsonParser.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("解析上传会话失败: %v", err)
+	}
+	return &session, nil
+}
+
+// saveUploadSession 保存分片上传会话
+func (a *App) saveUploadSession(session *UploadSession) error {
+	sessionPath := a.uploadSessionPath(session.Bucket, session.RemotePath, session.LocalPath)
+	if err := os.MkdirAll(filepath.Dir(sessionPath), 0755); err != nil {
+		return fmt.Errorf("创建上传会话目录失败: %v", err)
+	}
+
+	data, err := a.jsonParser.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("序列化上传会话失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致会话损坏
+	tmpPath := sessionPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入上传会话失败: %v", err)
+	}
+	return os.Rename(tmpPath, sessionPath)
+}
+
+// removeUploadSession 删除分片上传会话
+func (a *App) removeUploadSession(session *UploadSession) {
+	os.Remove(a.uploadSessionPath(session.Bucket, session.RemotePath, session.LocalPath))
+}
+
+// newUploadSession 在服务端创建新的分片上传并保存会话
+func (a *App) newUploadSession(ctx context.Context, core minio.Core, localPath, remotePath string, fileInfo os.FileInfo, checksum string) (*UploadSession, error) {
+	sse, err := a.serverSideFor(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	uploadID, err := core.NewMultipartUpload(ctx, a.minioConfig.BucketName, remotePath, minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		UserMetadata:         fileMetadata(fileInfo, checksum, a.preserveOwner(remotePath)),
+		ServerSideEncryption: sse,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建分片上传失败: %v", err)
+	}
+
+	session := &UploadSession{
+		UploadID:   uploadID,
+		Bucket:     a.minioConfig.BucketName,
+		RemotePath: remotePath,
+		LocalPath:  localPath,
+		Size:       fileInfo.Size(),
+		ModTime:    fileInfo.ModTime(),
+		PartSize:   resumablePartSize,
+		CreatedAt:  time.Now(),
+	}
+	if err := a.saveUploadSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// uploadFileResumable 以分片方式上传大文件
+// 每完成一个分片就保存会话，程序重启或网络中断后从最后完成的分片继续上传；
+// 文件在两次上传之间发生变化时放弃旧的分片上传并重新开始
+func (a *App) uploadFileResumable(ctx context.Context, file *os.File, localPath, remotePath string, fileInfo os.FileInfo, checksum string) (minio.UploadInfo, error) {
+	core := minio.Core{Client: a.minioClient}
+	bucket := a.minioConfig.BucketName
+
+	session, err := a.loadUploadSession(bucket, remotePath, localPath)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	if session != nil && !session.matches(fileInfo) {
+		core.AbortMultipartUpload(context.Background(), bucket, remotePath, session.UploadID)
+		a.removeUploadSession(session)
+		session = nil
+	}
+	if session == nil {
+		session, err = a.newUploadSession(ctx, core, localPath, remotePath, fileInfo, checksum)
+		if err != nil {
+			return minio.UploadInfo{}, err
+		}
+	} else {
+		fmt.Printf("继续分片上传: %s (已完成 %d/%d 字节)\n", remotePath, session.completedBytes(), session.Size)
+	}
+
+	info, err := a.uploadSessionParts(ctx, core, file, session)
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+		// 服务端的分片上传已失效（过期或被清理），重新开始一次
+		a.removeUploadSession(session)
+		session, err = a.newUploadSession(ctx, core, localPath, remotePath, fileInfo, checksum)
+		if err != nil {
+			return minio.UploadInfo{}, err
+		}
+		info, err = a.uploadSessionParts(ctx, core, file, session)
+	}
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("上传文件失败: %v", err)
+	}
+
+	a.removeUploadSession(session)
+	return info, nil
+}
+
+// uploadSessionParts 上传会话中尚未完成的分片并合并
+func (a *App) uploadSessionParts(ctx context.Context, core minio.Core, file *os.File, session *UploadSession) (minio.UploadInfo, error) {
+	// SSE-C 的每个分片都要携带创建上传时使用的密钥
+	sse, err := a.serverSideFor(session.RemotePath)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	progress := a.newTransferProgress(session.RemotePath, session.Size)
+	progress.done = session.completedBytes()
+
+	partCount := int((session.Size + session.PartSize - 1) / session.PartSize)
+	for partNumber := 1; partNumber <= partCount; partNumber++ {
+		if session.hasPart(partNumber) {
+			continue
+		}
+
+		// 暂停时在分片之间等待，取消时保留会话以便下次继续
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return minio.UploadInfo{}, err
+		}
+
+		offset := int64(partNumber-1) * session.PartSize
+		size := session.PartSize
+		if offset+size > session.Size {
+			size = session.Size - offset
+		}
+
+		reader := progress.wrap(a.limitUpload(ctx, io.NewSectionReader(file, offset, size)))
+		part, err := core.PutObjectPart(ctx, session.Bucket, session.RemotePath, session.UploadID, partNumber, reader, size, minio.PutObjectPartOptions{SSE: sse})
+		if err != nil {
+			return minio.UploadInfo{}, err
+		}
+
+		session.Parts = append(session.Parts, UploadedPart{PartNumber: partNumber, ETag: part.ETag, Size: size})
+		if err := a.saveUploadSession(session); err != nil {
+			return minio.UploadInfo{}, err
+		}
+	}
+
+	// 按分片编号合并
+	sort.Slice(session.Parts, func(i, j int) bool { return session.Parts[i].PartNumber < session.Parts[j].PartNumber })
+	completeParts := make([]minio.CompletePart, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	return core.CompleteMultipartUpload(ctx, session.Bucket, session.RemotePath, session.UploadID, completeParts, minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		ServerSideEncryption: sse,
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"mime"
 	"path/filepath"
@@ -31,6 +33,7 @@ func (a *App) UpdateMinioConfig(endpoint, accessKeyID, secretAccessKey, bucketNa
 		UseSSL:          useSSL,
 		BucketName:      bucketName,
 		Enabled:         enabled,
+		Encryption:      a.minioConfig.Encryption,
 	}
 
 	// 保存配置
@@ -110,20 +113,39 @@ func (a *App) UploadDataToMinio(data []byte, remotePath string) error {
 		contentType = "application/octet-stream"
 	}
 
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
+	enc, err := a.cipherForKey(remotePath)
+	if err != nil {
+		return err
+	}
+	if enc != nil {
+		_, err = a.putEncrypted(context.Background(), enc, remotePath, bytes.NewReader(data), int64(len(data)), map[string]string{}, checksum, nil)
+		return err
+	}
+
+	sse, err := a.serverSideFor(remotePath)
+	if err != nil {
+		return err
+	}
+
 	// 上传数据
-	_, err := a.minioClient.PutObject(
+	_, err = a.minioClient.PutObject(
 		context.Background(),
 		a.minioConfig.BucketName,
 		remotePath,
-		strings.NewReader(string(data)),
+		a.limitUpload(context.Background(), bytes.NewReader(data)),
 		int64(len(data)),
-		minio.PutObjectOptions{ContentType: contentType},
+		minio.PutObjectOptions{
+			ContentType:          contentType,
+			UserMetadata:         checksumMetadata(checksum),
+			ServerSideEncryption: sse,
+		},
 	)
 
 	return err
 }
 
-// DeleteFileFromMinio 从 MinIO 删除文件
+// DeleteFileFromMinio 从 MinIO 删除文件，文件移入当前用户的回收站
 func (a *App) DeleteFileFromMinio(remotePath string) error {
 	// 检查用户是否已登录
 	if !a.isLoggedIn {
@@ -135,8 +157,8 @@ func (a *App) DeleteFileFromMinio(remotePath string) error {
 		return fmt.Errorf("MinIO 未启用")
 	}
 
-	// 删除对象
-	return a.minioClient.RemoveObject(context.Background(), a.minioConfig.BucketName, remotePath, minio.RemoveObjectOptions{})
+	// 移入回收站，可以在自动清除前恢复
+	return a.moveToTrash(context.Background(), remotePath)
 }
 
 // ListMinioFilesByBucket 按存储桶列出MinIO中的文件
@@ -171,8 +193,8 @@ func (a *App) ListMinioFilesByBucket(bucketName, path string) ([]MinioFileInfo,
 			return nil, fmt.Errorf("列出对象失败: %v", object.Err)
 		}
 
-		// 跳过当前目录
-		if object.Key == path {
+		// 跳过当前目录、回收站和分块存储
+		if object.Key == path || isTrashKey(object.Key) || isChunkKey(object.Key) {
 			continue
 		}
 
@@ -191,3 +213,43 @@ func (a *App) ListMinioFilesByBucket(bucketName, path string) ([]MinioFileInfo,
 
 	return files, nil
 }
+
+// maxSingleCopySize 单次 CopyObject 支持的最大对象大小（5GiB），更大的对象需要分片复制
+const maxSingleCopySize = 5 * 1024 * 1024 * 1024
+
+// copyMinioObject 在服务端复制对象，不经过本地传输
+func (a *App) copyMinioObject(ctx context.Context, srcPath, dstPath string, size int64) (minio.UploadInfo, error) {
+	return a.copyMinioObjectVersion(ctx, srcPath, "", dstPath, size)
+}
+
+// copyMinioObjectVersion 在服务端复制对象的指定版本，versionID 为空时复制最新版本
+func (a *App) copyMinioObjectVersion(ctx context.Context, srcPath, versionID, dstPath string, size int64) (minio.UploadInfo, error) {
+	if a.minioClient == nil {
+		return minio.UploadInfo{}, fmt.Errorf("MinIO客户端未初始化")
+	}
+
+	src, dst, err := a.copyOptions(ctx, srcPath, versionID, dstPath)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// 超过单次复制上限时使用分片复制
+	if size > maxSingleCopySize {
+		return a.minioClient.ComposeObject(ctx, dst, src)
+	}
+	return a.minioClient.CopyObject(ctx, dst, src)
+}
+
+// moveMinioObject 在服务端移动对象（复制后删除源对象）
+func (a *App) moveMinioObject(ctx context.Context, srcPath, dstPath string, size int64) (minio.UploadInfo, error) {
+	info, err := a.copyMinioObject(ctx, srcPath, dstPath, size)
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("复制对象失败: %v", err)
+	}
+
+	if err := a.minioClient.RemoveObject(ctx, a.minioConfig.BucketName, srcPath, minio.RemoveObjectOptions{}); err != nil {
+		return info, fmt.Errorf("删除源对象失败: %v", err)
+	}
+
+	return info, nil
+}
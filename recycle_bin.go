@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// trashFolder 回收站目录，远程位于存储桶根目录，本地位于存储目录下，按用户分开
+	trashFolder = ".trash"
+	// trashTimeLayout 回收站中删除时间目录的格式
+	trashTimeLayout = "20060102T150405.000000000Z"
+	// trashPurgeInterval 检查回收站过期项目的间隔
+	trashPurgeInterval = 6 * time.Hour
+	// defaultTrashRetentionDays 新配置默认的回收站保留天数
+	defaultTrashRetentionDays = 30
+)
+
+// 移入回收站时写入对象用户元数据的信息
+const (
+	trashOriginalPathKey = "Original-Path" // 原始路径，经过URL编码
+	trashDeletedAtKey    = "Deleted-At"    // 删除时间，RFC3339
+	trashDeletedByKey    = "Deleted-By"    // 删除的用户
+	trashRuleKey         = "Rule"          // 原始路径所属的同步规则ID
+)
+
+// 回收站项目的位置
+const (
+	TrashLocationRemote = "remote" // MinIO 中的对象
+	TrashLocationLocal  = "local"  // 本地存储目录中的文件或文件夹
+)
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	RetentionDays int `json:"retentionDays"` // 超过该天数的项目自动清除，0 表示不自动清除
+}
+
+// TrashItem 回收站中的项目
+type TrashItem struct {
+	ID           string    `json:"id"` // 删除时间/原始路径
+	Location     string    `json:"location"`
+	OriginalPath string    `json:"originalPath"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	DeletedBy    string    `json:"deletedBy"`
+	Size         int64     `json:"size"`
+	IsDir        bool      `json:"isDir"`
+}
+
+// ListTrash 列出当前用户回收站中的项目，按删除时间从新到旧排列
+func (a *App) ListTrash() ([]TrashItem, error) {
+	if !a.isLoggedIn {
+		return nil, fmt.Errorf("用户未登录")
+	}
+
+	items, err := a.listLocalTrash(a.trashUser())
+	if err != nil {
+		return nil, err
+	}
+	if a.minioConfig.Enabled && a.minioClient != nil {
+		remote, err := a.listRemoteTrash(context.Background(), trashPrefix(a.trashUser()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, remote...)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+// RestoreTrashItem 将回收站中的项目恢复到原位置，原位置已有同名文件时不覆盖
+func (a *App) RestoreTrashItem(location, id string) error {
+	if !a.isLoggedIn {
+		return fmt.Errorf("用户未登录")
+	}
+	deletedAt, originalPath, err := parseTrashID(id)
+	if err != nil {
+		return err
+	}
+
+	switch location {
+	case TrashLocationLocal:
+		return a.restoreLocalTrash(a.trashUser(), deletedAt, originalPath)
+	case TrashLocationRemote:
+		if !a.minioConfig.Enabled || a.minioClient == nil {
+			return fmt.Errorf("MinIO 未启用")
+		}
+		return a.restoreRemoteTrash(context.Background(), trashPrefix(a.trashUser())+id, originalPath)
+	default:
+		return fmt.Errorf("无效的回收站位置: %s", location)
+	}
+}
+
+// EmptyTrash 彻底删除当前用户回收站中的所有项目
+func (a *App) EmptyTrash() error {
+	if !a.isLoggedIn {
+		return fmt.Errorf("用户未登录")
+	}
+
+	if err := os.RemoveAll(a.localTrashDir(a.trashUser())); err != nil {
+		return fmt.Errorf("清空本地回收站失败: %v", err)
+	}
+	if a.minioConfig.Enabled && a.minioClient != nil {
+		if err := a.removeRemotePrefix(context.Background(), trashPrefix(a.trashUser())); err != nil {
+			return fmt.Errorf("清空远程回收站失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetTrashConfig 获取回收站配置
+func (a *App) GetTrashConfig() TrashConfig {
+	return a.trashConfig
+}
+
+// SetTrashRetentionDays 设置回收站的保留天数，0 表示不自动清除
+func (a *App) SetTrashRetentionDays(days int) error {
+	if days < 0 {
+		return fmt.Errorf("保留天数不能为负数")
+	}
+	a.trashConfig.RetentionDays = days
+	return a.saveConfig()
+}
+
+// trashUser 回收站所属的用户，未登录时的同步删除归入 default
+func (a *App) trashUser() string {
+	if a.currentUser == "" {
+		return "default"
+	}
+	return a.currentUser
+}
+
+// trashPrefix 用户远程回收站的对象前缀
+func trashPrefix(user string) string {
+	return trashFolder + "/" + user + "/"
+}
+
+// isTrashKey 判断对象是否位于远程回收站中，列出同步文件时跳过
+func isTrashKey(key string) bool {
+	return strings.HasPrefix(key, trashFolder+"/")
+}
+
+// trashOriginalPath 从远程回收站对象键中解析原始路径，不是回收站对象时返回false
+func trashOriginalPath(key string) (string, bool) {
+	if !isTrashKey(key) {
+		return "", false
+	}
+	_, id, _ := strings.Cut(strings.TrimPrefix(key, trashFolder+"/"), "/")
+	_, originalPath, err := parseTrashID(id)
+	if err != nil {
+		return "", false
+	}
+	return originalPath, true
+}
+
+// localTrashDir 用户本地回收站目录
+func (a *App) localTrashDir(user string) string {
+	return filepath.Join(a.storagePath, trashFolder, user)
+}
+
+// trashID 由删除时间和原始路径组成回收站项目ID
+func trashID(deletedAt time.Time, originalPath string) string {
+	return deletedAt.UTC().Format(trashTimeLayout) + "/" + originalPath
+}
+
+// parseTrashID 解析回收站项目ID
+func parseTrashID(id string) (time.Time, string, error) {
+	stamp, originalPath, ok := strings.Cut(id, "/")
+	if !ok || originalPath == "" {
+		return time.Time{}, "", fmt.Errorf("无效的回收站项目: %s", id)
+	}
+	deletedAt, err := time.Parse(trashTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("无效的回收站项目: %s", id)
+	}
+	return deletedAt, originalPath, nil
+}
+
+// moveToTrash 将远程对象移入回收站，对象不存在时视为已删除
+func (a *App) moveToTrash(ctx context.Context, remotePath string) error {
+	info, err := a.statObject(ctx, remotePath, "")
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil
+		}
+		return fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+
+	deletedAt := time.Now()
+	metadata := withoutTrashMetadata(info.UserMetadata)
+	metadata[trashOriginalPathKey] = url.PathEscape(remotePath)
+	metadata[trashDeletedAtKey] = deletedAt.UTC().Format(time.RFC3339)
+	metadata[trashDeletedByKey] = a.trashUser()
+	if rule, ok := a.ruleForKey(remotePath, func(SyncRule) bool { return true }); ok {
+		metadata[trashRuleKey] = rule.ID
+	}
+
+	trashKey := trashPrefix(a.trashUser()) + trashID(deletedAt, remotePath)
+	if err := a.copyObjectWithMetadata(ctx, remotePath, trashKey, info, metadata); err != nil {
+		return fmt.Errorf("移入回收站失败: %v", err)
+	}
+	if err := a.minioClient.RemoveObject(ctx, a.minioConfig.BucketName, remotePath, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("删除源对象失败: %v", err)
+	}
+	return nil
+}
+
+// moveLocalToTrash 将本地存储目录中的文件或文件夹移入本地回收站
+func (a *App) moveLocalToTrash(path string) error {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if !validTrashPath(path) {
+		return fmt.Errorf("无效的路径: %s", path)
+	}
+
+	fullPath := filepath.Join(a.storagePath, filepath.FromSlash(path))
+	if _, err := os.Lstat(fullPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// 原始路径编码为单个文件名，避免与同一时间删除的其他项目混在一起
+	trashPath := filepath.Join(a.localTrashDir(a.trashUser()), time.Now().UTC().Format(trashTimeLayout), url.PathEscape(path))
+	if err := os.MkdirAll(filepath.Dir(trashPath), 0755); err != nil {
+		return fmt.Errorf("创建回收站目录失败: %v", err)
+	}
+	if err := os.Rename(fullPath, trashPath); err != nil {
+		return fmt.Errorf("移入回收站失败: %v", err)
+	}
+	return nil
+}
+
+// validTrashPath 检查存储目录中的相对路径，拒绝回收站本身和存储目录之外的路径
+func validTrashPath(path string) bool {
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") || filepath.IsAbs(path) {
+		return false
+	}
+	return path != trashFolder && !strings.HasPrefix(path, trashFolder+"/")
+}
+
+// copyObjectWithMetadata 在服务端复制对象并替换用户元数据
+func (a *App) copyObjectWithMetadata(ctx context.Context, srcPath, dstPath string, info minio.ObjectInfo, metadata map[string]string) error {
+	src, dst, err := a.copyOptions(ctx, srcPath, "", dstPath)
+	if err != nil {
+		return err
+	}
+	dst.ReplaceMetadata = true
+	dst.UserMetadata = metadata
+	dst.ContentType = info.ContentType
+
+	if info.Size > maxSingleCopySize {
+		_, err = a.minioClient.ComposeObject(ctx, dst, src)
+	} else {
+		_, err = a.minioClient.CopyObject(ctx, dst, src)
+	}
+	return err
+}
+
+// withoutTrashMetadata 复制用户元数据并去掉回收站信息
+func withoutTrashMetadata(metadata map[string]string) map[string]string {
+	result := make(map[string]string)
+	for k, v := range metadata {
+		name := strings.TrimPrefix(k, "X-Amz-Meta-")
+		if strings.EqualFold(name, trashOriginalPathKey) || strings.EqualFold(name, trashDeletedAtKey) ||
+			strings.EqualFold(name, trashDeletedByKey) || strings.EqualFold(name, trashRuleKey) {
+			continue
+		}
+		result[name] = v
+	}
+	return result
+}
+
+// listRemoteTrash 列出前缀下的远程回收站项目
+func (a *App) listRemoteTrash(ctx context.Context, prefix string) ([]TrashItem, error) {
+	var items []TrashItem
+	objectCh := a.minioClient.ListObjects(ctx, a.minioConfig.BucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("列出回收站失败: %v", object.Err)
+		}
+
+		// 键为 .trash/用户/删除时间/原始路径
+		user, id, _ := strings.Cut(strings.TrimPrefix(object.Key, trashFolder+"/"), "/")
+		deletedAt, originalPath, err := parseTrashID(id)
+		if err != nil {
+			continue
+		}
+		items = append(items, TrashItem{
+			ID:           id,
+			Location:     TrashLocationRemote,
+			OriginalPath: originalPath,
+			DeletedAt:    deletedAt,
+			DeletedBy:    user,
+			Size:         object.Size,
+		})
+	}
+	return items, nil
+}
+
+// listLocalTrash 列出用户的本地回收站项目
+func (a *App) listLocalTrash(user string) ([]TrashItem, error) {
+	var items []TrashItem
+	stamps, err := os.ReadDir(a.localTrashDir(user))
+	if os.IsNotExist(err) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取回收站失败: %v", err)
+	}
+
+	for _, stamp := range stamps {
+		deletedAt, err := time.Parse(trashTimeLayout, stamp.Name())
+		if err != nil {
+			continue
+		}
+		stampDir := filepath.Join(a.localTrashDir(user), stamp.Name())
+		entries, err := os.ReadDir(stampDir)
+		if err != nil {
+			return nil, fmt.Errorf("读取回收站失败: %v", err)
+		}
+		for _, entry := range entries {
+			originalPath, err := url.PathUnescape(entry.Name())
+			if err != nil {
+				continue
+			}
+			size, err := localTreeSize(filepath.Join(stampDir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, TrashItem{
+				ID:           trashID(deletedAt, originalPath),
+				Location:     TrashLocationLocal,
+				OriginalPath: originalPath,
+				DeletedAt:    deletedAt,
+				DeletedBy:    user,
+				Size:         size,
+				IsDir:        entry.IsDir(),
+			})
+		}
+	}
+	return items, nil
+}
+
+// localTreeSize 统计文件或文件夹的总大小
+func localTreeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("统计回收站项目大小失败: %v", err)
+	}
+	return size, nil
+}
+
+// restoreRemoteTrash 将回收站对象移回原始路径，并去掉回收站元数据
+func (a *App) restoreRemoteTrash(ctx context.Context, trashKey, originalPath string) error {
+	info, err := a.statObject(ctx, trashKey, "")
+	if err != nil {
+		return fmt.Errorf("回收站中没有该项目: %v", err)
+	}
+
+	if _, err := a.statObject(ctx, originalPath, ""); err == nil {
+		return fmt.Errorf("原位置已存在同名文件: %s", originalPath)
+	} else if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+
+	if err := a.copyObjectWithMetadata(ctx, trashKey, originalPath, info, withoutTrashMetadata(info.UserMetadata)); err != nil {
+		return fmt.Errorf("恢复文件失败: %v", err)
+	}
+	if err := a.minioClient.RemoveObject(ctx, a.minioConfig.BucketName, trashKey, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("删除回收站对象失败: %v", err)
+	}
+	return nil
+}
+
+// restoreLocalTrash 将本地回收站项目移回存储目录中的原始位置
+func (a *App) restoreLocalTrash(user string, deletedAt time.Time, originalPath string) error {
+	if !validTrashPath(filepath.ToSlash(filepath.Clean(originalPath))) {
+		return fmt.Errorf("无效的路径: %s", originalPath)
+	}
+
+	stampDir := filepath.Join(a.localTrashDir(user), deletedAt.UTC().Format(trashTimeLayout))
+	trashPath := filepath.Join(stampDir, url.PathEscape(originalPath))
+	if _, err := os.Lstat(trashPath); err != nil {
+		return fmt.Errorf("回收站中没有该项目: %s", originalPath)
+	}
+
+	fullPath := filepath.Join(a.storagePath, filepath.FromSlash(originalPath))
+	if _, err := os.Lstat(fullPath); err == nil {
+		return fmt.Errorf("原位置已存在同名文件: %s", originalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := os.Rename(trashPath, fullPath); err != nil {
+		return fmt.Errorf("恢复文件失败: %v", err)
+	}
+
+	// 删除已空的时间目录
+	os.Remove(stampDir)
+	return nil
+}
+
+// purgeTrash 清除所有用户回收站中超过保留天数的项目
+func (a *App) purgeTrash(ctx context.Context) error {
+	if a.trashConfig.RetentionDays <= 0 {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -a.trashConfig.RetentionDays)
+
+	// 本地回收站：删除过期的时间目录
+	users, err := os.ReadDir(filepath.Join(a.storagePath, trashFolder))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取回收站失败: %v", err)
+	}
+	for _, user := range users {
+		stamps, err := os.ReadDir(a.localTrashDir(user.Name()))
+		if err != nil {
+			continue
+		}
+		for _, stamp := range stamps {
+			deletedAt, err := time.Parse(trashTimeLayout, stamp.Name())
+			if err != nil || deletedAt.After(cutoff) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(a.localTrashDir(user.Name()), stamp.Name())); err != nil {
+				return fmt.Errorf("清除回收站项目失败: %v", err)
+			}
+		}
+	}
+
+	if !a.minioConfig.Enabled || a.minioClient == nil {
+		return nil
+	}
+	return a.removeRemoteObjects(ctx, trashFolder+"/", func(object minio.ObjectInfo) bool {
+		_, id, _ := strings.Cut(strings.TrimPrefix(object.Key, trashFolder+"/"), "/")
+		deletedAt, _, err := parseTrashID(id)
+		return err != nil || deletedAt.After(cutoff)
+	})
+}
+
+// trashPurgeService 定期清除回收站中的过期项目
+func (a *App) trashPurgeService(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.purgeTrash(ctx); err != nil {
+			fmt.Printf("清除回收站过期项目失败: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
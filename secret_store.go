@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// secretsFileName 本机密钥存储文件，只有当前用户可读写，不随配置文件导出
+const secretsFileName = "secrets.json"
+
+// secretsPath 密钥存储文件路径
+func (a *App) secretsPath() string {
+	return filepath.Join(a.configDir, secretsFileName)
+}
+
+// loadSecrets 读取全部密钥，文件不存在时返回空表，调用方需持有 secretMu
+func (a *App) loadSecrets() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := os.ReadFile(a.secretsPath())
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥存储失败: %v", err)
+	}
+	if err := a.jsonParser.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("解析密钥存储失败: %v", err)
+	}
+	return secrets, nil
+}
+
+// saveSecrets 写入全部密钥，调用方需持有 secretMu
+func (a *App) saveSecrets(secrets map[string]string) error {
+	data, err := a.jsonParser.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("序列化密钥存储失败: %v", err)
+	}
+	if err := os.MkdirAll(a.configDir, 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	if err := os.WriteFile(a.secretsPath(), data, 0600); err != nil {
+		return fmt.Errorf("保存密钥存储失败: %v", err)
+	}
+	return nil
+}
+
+// getSecret 读取指定名称的密钥，不存在时返回空字符串
+func (a *App) getSecret(name string) (string, error) {
+	a.secretMu.Lock()
+	defer a.secretMu.Unlock()
+
+	secrets, err := a.loadSecrets()
+	if err != nil {
+		return "", err
+	}
+	return secrets[name], nil
+}
+
+// setSecret 保存指定名称的密钥
+func (a *App) setSecret(name, value string) error {
+	a.secretMu.Lock()
+	defer a.secretMu.Unlock()
+
+	secrets, err := a.loadSecrets()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return a.saveSecrets(secrets)
+}
+
+// deleteSecret 删除指定名称的密钥，不存在时不报错
+func (a *App) deleteSecret(name string) error {
+	a.secretMu.Lock()
+	defer a.secretMu.Unlock()
+
+	secrets, err := a.loadSecrets()
+	if err != nil {
+		return err
+	}
+	if _, exists := secrets[name]; !exists {
+		return nil
+	}
+	delete(secrets, name)
+	return a.saveSecrets(secrets)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// 服务端加密方式
+const (
+	ServerEncryptionInherit = ""       // 规则使用全局设置
+	ServerEncryptionNone    = "none"   // 不加密
+	ServerEncryptionS3      = "SSE-S3" // 服务端管理密钥
+	ServerEncryptionC       = "SSE-C"  // 客户提供密钥，密钥保存在本机密钥存储中，每次请求都要携带
+)
+
+// ServerEncryption 服务端加密设置
+type ServerEncryption struct {
+	Mode string `json:"mode"`
+}
+
+// customerKeySize SSE-C 密钥长度
+const customerKeySize = 32
+
+// GetServerEncryption 获取全局服务端加密设置
+func (a *App) GetServerEncryption() ServerEncryption {
+	return a.minioConfig.Encryption
+}
+
+// SetServerEncryption 设置全局服务端加密，只影响之后写入的对象
+// 使用 SSE-C 时 customerKey 为 Base64 编码的32字节密钥，为空时沿用已保存的密钥或生成新密钥
+func (a *App) SetServerEncryption(mode, customerKey string) error {
+	if !a.isLoggedIn {
+		return fmt.Errorf("用户未登录")
+	}
+	if mode == ServerEncryptionInherit {
+		mode = ServerEncryptionNone
+	}
+	if err := a.prepareServerEncryption(customerKeySecret(""), mode, customerKey); err != nil {
+		return err
+	}
+
+	a.minioConfig.Encryption = ServerEncryption{Mode: mode}
+	return a.saveConfig()
+}
+
+// SetRuleServerEncryption 设置规则的服务端加密，mode 为空表示使用全局设置
+func (a *App) SetRuleServerEncryption(ruleID, mode, customerKey string) error {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+	if err := a.prepareServerEncryption(customerKeySecret(ruleID), mode, customerKey); err != nil {
+		return err
+	}
+
+	rule.ServerEncryption = ServerEncryption{Mode: mode}
+	return a.UpdateSyncRule(rule)
+}
+
+// ExportServerEncryptionKey 导出 SSE-C 密钥，其他设备导入相同的密钥才能读取对象
+// ruleID 为空表示全局密钥
+func (a *App) ExportServerEncryptionKey(ruleID string) (string, error) {
+	if !a.isLoggedIn {
+		return "", fmt.Errorf("用户未登录")
+	}
+	key, err := a.getSecret(customerKeySecret(ruleID))
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", fmt.Errorf("没有保存的 SSE-C 密钥")
+	}
+	return key, nil
+}
+
+// prepareServerEncryption 检查加密方式，使用 SSE-C 时保存或生成密钥
+// 切换到其他方式时保留已保存的密钥，之前写入的对象仍需要它才能读取
+func (a *App) prepareServerEncryption(secret, mode, customerKey string) error {
+	switch mode {
+	case ServerEncryptionInherit, ServerEncryptionNone, ServerEncryptionS3:
+		if customerKey != "" {
+			return fmt.Errorf("只有 SSE-C 需要提供密钥")
+		}
+		return nil
+	case ServerEncryptionC:
+	default:
+		return fmt.Errorf("无效的服务端加密方式: %s", mode)
+	}
+
+	if customerKey != "" {
+		if _, err := decodeCustomerKey(customerKey); err != nil {
+			return err
+		}
+		return a.setSecret(secret, customerKey)
+	}
+
+	existing, err := a.getSecret(secret)
+	if err != nil || existing != "" {
+		return err
+	}
+	key := make([]byte, customerKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("生成密钥失败: %v", err)
+	}
+	return a.setSecret(secret, base64.StdEncoding.EncodeToString(key))
+}
+
+// customerKeySecret SSE-C 密钥在密钥存储中的名称，ruleID 为空表示全局密钥
+func customerKeySecret(ruleID string) string {
+	if ruleID == "" {
+		return "sse-c/default"
+	}
+	return "sse-c/" + ruleID
+}
+
+// decodeCustomerKey 解析 Base64 编码的 SSE-C 密钥
+func decodeCustomerKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != customerKeySize {
+		return nil, fmt.Errorf("SSE-C 密钥必须是 Base64 编码的 %d 字节密钥", customerKeySize)
+	}
+	return key, nil
+}
+
+// customerKey 读取密钥存储中的 SSE-C 密钥，没有保存时返回nil
+func (a *App) customerKey(ruleID string) (encrypt.ServerSide, error) {
+	encoded, err := a.getSecret(customerKeySecret(ruleID))
+	if err != nil || encoded == "" {
+		return nil, err
+	}
+	key, err := decodeCustomerKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+	sse, err := encrypt.NewSSEC(key)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 SSE-C 密钥: %v", err)
+	}
+	return sse, nil
+}
+
+// serverEncryptionScope 对象适用的服务端加密方式和密钥所属的规则ID
+// 对象属于设置了服务端加密的规则时使用规则的设置，否则使用全局设置；回收站中的对象按原始路径确定
+func (a *App) serverEncryptionScope(key string) (string, string) {
+	if originalPath, ok := trashOriginalPath(key); ok {
+		key = originalPath
+	}
+	rule, ok := a.ruleForKey(key, func(rule SyncRule) bool { return rule.ServerEncryption.Mode != ServerEncryptionInherit })
+	if ok {
+		return rule.ServerEncryption.Mode, rule.ID
+	}
+	return a.minioConfig.Encryption.Mode, ""
+}
+
+// serverSideFor 写入对象时使用的服务端加密，不加密时返回nil
+func (a *App) serverSideFor(key string) (encrypt.ServerSide, error) {
+	mode, ruleID := a.serverEncryptionScope(key)
+	switch mode {
+	case ServerEncryptionS3:
+		return encrypt.NewSSE(), nil
+	case ServerEncryptionC:
+		sse, err := a.customerKey(ruleID)
+		if err != nil {
+			return nil, err
+		}
+		if sse == nil {
+			return nil, fmt.Errorf("未找到 SSE-C 密钥，请重新设置服务端加密或导入密钥")
+		}
+		return sse, nil
+	}
+	return nil, nil
+}
+
+// readCustomerKeys 读取对象时依次尝试的 SSE-C 密钥，nil 表示不带密钥
+// 对象可能在加密设置变更前写入，当前设置优先，再尝试规则和全局保存的其他密钥；
+// 回收站中的对象按原始路径所属的规则查找，规则的远程路径可能已经修改，再尝试其他规则的密钥
+func (a *App) readCustomerKeys(key string) ([]encrypt.ServerSide, error) {
+	mode, _ := a.serverEncryptionScope(key)
+	var keys []encrypt.ServerSide
+	if mode != ServerEncryptionC {
+		keys = append(keys, nil)
+	}
+
+	scopePath, trashed := trashOriginalPath(key)
+	if !trashed {
+		scopePath = key
+	}
+	var scopes []string
+	if rule, ok := a.ruleForKey(scopePath, func(SyncRule) bool { return true }); ok {
+		scopes = append(scopes, rule.ID)
+	}
+	if trashed {
+		for _, rule := range a.syncRules {
+			if len(scopes) == 0 || rule.ID != scopes[0] {
+				scopes = append(scopes, rule.ID)
+			}
+		}
+	}
+	scopes = append(scopes, "")
+	for _, ruleID := range scopes {
+		sse, err := a.customerKey(ruleID)
+		if err != nil {
+			return nil, err
+		}
+		if sse != nil {
+			keys = append(keys, sse)
+		}
+	}
+
+	if mode == ServerEncryptionC {
+		keys = append(keys, nil)
+	}
+	return keys, nil
+}
+
+// statObjectSSE 获取对象信息，并返回读取该对象需要携带的 SSE-C 密钥
+// 密钥不匹配时服务端返回 400，依次尝试其他密钥
+func (a *App) statObjectSSE(ctx context.Context, key, versionID string) (minio.ObjectInfo, encrypt.ServerSide, error) {
+	keys, err := a.readCustomerKeys(key)
+	if err != nil {
+		return minio.ObjectInfo{}, nil, err
+	}
+
+	var lastErr error
+	for _, sse := range keys {
+		info, err := a.minioClient.StatObject(ctx, a.minioConfig.BucketName, key, minio.StatObjectOptions{
+			ServerSideEncryption: sse,
+			VersionID:            versionID,
+		})
+		if err == nil {
+			return info, sse, nil
+		}
+		if minio.ToErrorResponse(err).StatusCode != http.StatusBadRequest {
+			return minio.ObjectInfo{}, nil, err
+		}
+		lastErr = err
+	}
+	return minio.ObjectInfo{}, nil, lastErr
+}
+
+// statObject 获取对象信息，自动携带对象需要的 SSE-C 密钥
+func (a *App) statObject(ctx context.Context, key, versionID string) (minio.ObjectInfo, error) {
+	info, _, err := a.statObjectSSE(ctx, key, versionID)
+	return info, err
+}
+
+// getObject 读取对象，自动携带对象需要的 SSE-C 密钥，同时返回对象信息
+func (a *App) getObject(ctx context.Context, key, versionID string) (*minio.Object, minio.ObjectInfo, error) {
+	info, sse, err := a.statObjectSSE(ctx, key, versionID)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	obj, err := a.minioClient.GetObject(ctx, a.minioConfig.BucketName, key, minio.GetObjectOptions{
+		ServerSideEncryption: sse,
+		VersionID:            versionID,
+	})
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	return obj, info, nil
+}
+
+// copyOptions 服务端复制的源和目标选项：源对象携带读取所需的密钥，目标按目标路径的设置加密
+// 移入回收站时目标按原始路径的设置加密，使用 SSE-C 的对象沿用原来的密钥，删除不会降低对象的加密保护
+func (a *App) copyOptions(ctx context.Context, srcPath, versionID, dstPath string) (minio.CopySrcOptions, minio.CopyDestOptions, error) {
+	_, srcSSE, err := a.statObjectSSE(ctx, srcPath, versionID)
+	if err != nil {
+		return minio.CopySrcOptions{}, minio.CopyDestOptions{}, err
+	}
+	dstSSE, err := a.serverSideFor(dstPath)
+	if err != nil {
+		return minio.CopySrcOptions{}, minio.CopyDestOptions{}, err
+	}
+	if srcSSE != nil && isTrashKey(dstPath) {
+		dstSSE = srcSSE
+	}
+
+	src := minio.CopySrcOptions{Bucket: a.minioConfig.BucketName, Object: srcPath, VersionID: versionID, Encryption: srcSSE}
+	dst := minio.CopyDestOptions{Bucket: a.minioConfig.BucketName, Object: dstPath, Encryption: dstSSE}
+	return src, dst, nil
+}
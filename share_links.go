@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultShareAddress 分享网关默认监听地址
+	defaultShareAddress = "127.0.0.1:18765"
+	// sharePresignExpiry 网关跳转的预签名地址有效期，只需要够浏览器发起下载
+	// 有效期内地址可以重复使用，过长会绕过访问次数和有效期的限制
+	sharePresignExpiry = 10 * time.Second
+	// sharePathPrefix 分享链接的URL路径前缀
+	sharePathPrefix = "/s/"
+	// sharePasswordMaxFailures 同一分享链接连续输错密码的次数上限，达到后暂停验证
+	sharePasswordMaxFailures = 5
+	// sharePasswordLockout 输错次数达到上限后暂停验证的时长
+	sharePasswordLockout = 5 * time.Minute
+)
+
+// shareAttempt 分享链接的密码尝试记录
+type shareAttempt struct {
+	failures     int
+	pending      int // 正在验证的次数
+	blockedUntil time.Time
+}
+
+// ShareConfig 分享网关配置
+type ShareConfig struct {
+	Address string `json:"address"` // 网关监听地址，如 127.0.0.1:18765
+	BaseURL string `json:"baseUrl"` // 生成链接使用的外部地址，为空时使用监听地址
+}
+
+// shareGateway 本地分享网关
+// 预签名地址本身无法限制密码和访问次数，分享链接先经过网关校验，再跳转到短期有效的预签名地址
+type shareGateway struct {
+	server  *http.Server
+	address string
+}
+
+// CreateShareLink 为远程文件创建分享链接
+// expiryHours 为 0 表示永不过期，password 为空表示不需要密码，maxViews 为 0 表示不限访问次数
+func (a *App) CreateShareLink(path string, expiryHours int, password string, maxViews int) (ShareLink, error) {
+	if !a.isLoggedIn {
+		return ShareLink{}, fmt.Errorf("用户未登录")
+	}
+	if !a.minioConfig.Enabled || a.minioClient == nil {
+		return ShareLink{}, fmt.Errorf("MinIO 未启用")
+	}
+	if expiryHours < 0 || maxViews < 0 {
+		return ShareLink{}, fmt.Errorf("有效期和访问次数不能为负数")
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	if path == "" || strings.HasSuffix(path, "/") {
+		return ShareLink{}, fmt.Errorf("只能分享文件: %s", path)
+	}
+	if err := a.checkShareable(context.Background(), path); err != nil {
+		return ShareLink{}, err
+	}
+
+	id, err := newShareID()
+	if err != nil {
+		return ShareLink{}, err
+	}
+
+	link := ShareLink{
+		ID:        id,
+		Path:      path,
+		URL:       a.shareBaseURL() + sharePathPrefix + id,
+		MaxViews:  maxViews,
+		CreatedAt: time.Now(),
+		CreatedBy: a.currentUser,
+	}
+	if expiryHours > 0 {
+		link.ExpiresAt = link.CreatedAt.Add(time.Duration(expiryHours) * time.Hour)
+	}
+	// 只保存密码的哈希值
+	if password != "" {
+		link.Password, err = hashPassword(password)
+		if err != nil {
+			return ShareLink{}, err
+		}
+	}
+
+	a.shareMu.Lock()
+	a.shareLinks[id] = link
+	err = a.saveShareLinks()
+	a.shareMu.Unlock()
+	if err != nil {
+		return ShareLink{}, err
+	}
+
+	if err := a.startShareGateway(); err != nil {
+		return ShareLink{}, err
+	}
+	return publicShareLink(link), nil
+}
+
+// ListShareLinks 列出所有分享链接，按创建时间从新到旧排列
+func (a *App) ListShareLinks() []ShareLink {
+	a.shareMu.Lock()
+	defer a.shareMu.Unlock()
+
+	links := make([]ShareLink, 0, len(a.shareLinks))
+	for _, link := range a.shareLinks {
+		links = append(links, publicShareLink(link))
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.After(links[j].CreatedAt) })
+	return links
+}
+
+// RevokeShareLink 撤销分享链接，撤销后链接立即失效
+func (a *App) RevokeShareLink(id string) error {
+	a.shareMu.Lock()
+	defer a.shareMu.Unlock()
+
+	if _, exists := a.shareLinks[id]; !exists {
+		return fmt.Errorf("分享链接不存在: %s", id)
+	}
+	delete(a.shareLinks, id)
+	delete(a.shareAttempts, id)
+	return a.saveShareLinks()
+}
+
+// GetShareConfig 获取分享网关配置
+func (a *App) GetShareConfig() ShareConfig {
+	return a.shareConfig
+}
+
+// UpdateShareConfig 更新分享网关配置，网关正在运行时使用新地址重新启动
+func (a *App) UpdateShareConfig(config ShareConfig) error {
+	if config.Address == "" {
+		config.Address = defaultShareAddress
+	}
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return fmt.Errorf("无效的监听地址: %v", err)
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.BaseURL != "" {
+		if u, err := url.Parse(config.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("无效的外部地址: %s", config.BaseURL)
+		}
+	}
+
+	a.shareConfig = config
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+
+	a.shareMu.Lock()
+	running := a.shareGateway != nil
+	a.shareMu.Unlock()
+	if running {
+		a.stopShareGateway()
+		return a.startShareGateway()
+	}
+	return nil
+}
+
+// newShareID 生成随机的分享链接ID，同时作为链接中不可猜测的令牌
+func newShareID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成分享链接ID失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// publicShareLink 返回给前端的链接信息，不包含密码哈希
+func publicShareLink(link ShareLink) ShareLink {
+	if link.Password != "" {
+		link.Password = "******"
+	}
+	return link
+}
+
+// shareBaseURL 生成分享链接使用的地址
+func (a *App) shareBaseURL() string {
+	if a.shareConfig.BaseURL != "" {
+		return a.shareConfig.BaseURL
+	}
+	if a.shareConfig.Address != "" {
+		return "http://" + a.shareConfig.Address
+	}
+	return "http://" + defaultShareAddress
+}
+
+// shareLinksPath 分享链接文件路径
+func (a *App) shareLinksPath() string {
+	return filepath.Join(a.configDir, "share_links.json")
+}
+
+// loadShareLinks 加载保存的分享链接
+func (a *App) loadShareLinks() error {
+	data, err := os.ReadFile(a.shareLinksPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取分享链接文件失败: %v", err)
+	}
+
+	var links []ShareLink
+	if err := a.jsonParser.Unmarshal(data, &links); err != nil {
+		return fmt.Errorf("解析分享链接失败: %v", err)
+	}
+
+	a.shareMu.Lock()
+	defer a.shareMu.Unlock()
+	for _, link := range links {
+		a.shareLinks[link.ID] = link
+	}
+	return nil
+}
+
+// saveShareLinks 保存分享链接，调用方需持有 a.shareMu
+func (a *App) saveShareLinks() error {
+	links := make([]ShareLink, 0, len(a.shareLinks))
+	for _, link := range a.shareLinks {
+		links = append(links, link)
+	}
+
+	data, err := a.jsonParser.Marshal(links)
+	if err != nil {
+		return fmt.Errorf("序列化分享链接失败: %v", err)
+	}
+	if err := os.WriteFile(a.shareLinksPath(), data, 0600); err != nil {
+		return fmt.Errorf("写入分享链接文件失败: %v", err)
+	}
+	return nil
+}
+
+// initShareLinks 加载分享链接，有链接时启动分享网关
+func (a *App) initShareLinks() error {
+	if err := a.loadShareLinks(); err != nil {
+		return err
+	}
+
+	a.shareMu.Lock()
+	count := len(a.shareLinks)
+	a.shareMu.Unlock()
+	if count == 0 {
+		return nil
+	}
+	return a.startShareGateway()
+}
+
+// startShareGateway 启动分享网关，已在运行时不做任何操作
+func (a *App) startShareGateway() error {
+	a.shareMu.Lock()
+	defer a.shareMu.Unlock()
+	if a.shareGateway != nil {
+		return nil
+	}
+
+	address := a.shareConfig.Address
+	if address == "" {
+		address = defaultShareAddress
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("启动分享网关失败: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(sharePathPrefix, a.handleShareRequest)
+	gateway := &shareGateway{
+		server:  &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		address: listener.Addr().String(),
+	}
+	a.shareGateway = gateway
+
+	go func() {
+		if err := gateway.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("分享网关异常退出: %v\n", err)
+		}
+	}()
+	fmt.Printf("分享网关已启动: %s\n", gateway.address)
+	return nil
+}
+
+// stopShareGateway 停止分享网关
+func (a *App) stopShareGateway() {
+	a.shareMu.Lock()
+	gateway := a.shareGateway
+	a.shareGateway = nil
+	a.shareMu.Unlock()
+	if gateway == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	gateway.server.Shutdown(ctx)
+}
+
+// handleShareRequest 校验分享链接的有效期、访问次数和密码，通过后跳转到预签名下载地址
+func (a *App) handleShareRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+		return
+	}
+	if a.minioClient == nil {
+		http.Error(w, "MinIO 未启用", http.StatusServiceUnavailable)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, sharePathPrefix)
+
+	a.shareMu.Lock()
+	link, status, message := a.availableShareLink(id)
+	a.shareMu.Unlock()
+	if status != 0 {
+		http.Error(w, message, status)
+		return
+	}
+
+	// 密码只通过表单提交，避免出现在地址和访问日志中
+	// 密码哈希计算耗时且占用较多内存，在锁外进行，并按链接限制尝试次数
+	rehash := ""
+	if link.Password != "" {
+		password := ""
+		if r.Method == http.MethodPost {
+			password = r.PostFormValue("password")
+		}
+		if password == "" {
+			writeSharePasswordForm(w, link, http.StatusOK, false)
+			return
+		}
+
+		a.shareMu.Lock()
+		allowed := a.reserveSharePasswordAttempt(id)
+		a.shareMu.Unlock()
+		if !allowed {
+			http.Error(w, "密码错误次数过多，请稍后再试", http.StatusTooManyRequests)
+			return
+		}
+
+		ok, outdated := verifyPassword(password, link.Password)
+		a.shareMu.Lock()
+		a.finishSharePasswordAttempt(id, ok)
+		a.shareMu.Unlock()
+		if !ok {
+			writeSharePasswordForm(w, link, http.StatusForbidden, true)
+			return
+		}
+		// 旧版本的无盐哈希随访问次数一起更新
+		if outdated {
+			if hash, err := hashPassword(password); err == nil {
+				rehash = hash
+			}
+		}
+	}
+
+	// 文件可能在创建链接后被重新上传为加密、压缩或分块存储的对象，每次访问前重新检查
+	if err := a.checkShareable(r.Context(), link.Path); err != nil {
+		fmt.Printf("分享链接 %s 不可用: %v\n", id, err)
+		http.Error(w, "文件当前不能通过分享链接下载", http.StatusGone)
+		return
+	}
+
+	// 验证期间链接可能被撤销或访问次数已被其他请求用完，重新检查后再计入访问次数，保证并发访问不会超过限制
+	a.shareMu.Lock()
+	current, status, message := a.availableShareLink(id)
+	if status != 0 {
+		a.shareMu.Unlock()
+		http.Error(w, message, status)
+		return
+	}
+	if rehash != "" && current.Password == link.Password {
+		current.Password = rehash
+	}
+	current.Views++
+	a.shareLinks[id] = current
+	if err := a.saveShareLinks(); err != nil {
+		fmt.Printf("保存分享链接失败: %v\n", err)
+	}
+	a.shareMu.Unlock()
+	link = current
+
+	params := url.Values{}
+	params.Set("response-content-disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(filepath.Base(link.Path))))
+	presigned, err := a.minioClient.PresignedGetObject(r.Context(), a.minioConfig.BucketName, link.Path, sharePresignExpiry, params)
+	if err != nil {
+		http.Error(w, "生成下载地址失败", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, presigned.String(), http.StatusFound)
+}
+
+// checkShareable 检查对象能否通过预签名地址直接分享
+// 网关直接跳转到存储的对象，只有未经客户端转换的对象访问者才能直接使用
+func (a *App) checkShareable(ctx context.Context, path string) error {
+	info, sse, err := a.statObjectSSE(ctx, path, "")
+	if err != nil {
+		return fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+	// 预签名链接无法携带 SSE-C 密钥
+	if sse != nil {
+		return fmt.Errorf("使用 SSE-C 加密的文件不能分享: %s", path)
+	}
+	// 访问者无法解密
+	if metadataValue(info.UserMetadata, encryptionMetadataKey) != "" {
+		return fmt.Errorf("客户端加密的文件不能分享: %s", path)
+	}
+	// 访问者会下载到压缩后的内容
+	if metadataValue(info.UserMetadata, compressionMetadataKey) != "" {
+		return fmt.Errorf("压缩存储的文件不能分享: %s", path)
+	}
+	// 分块存储的对象只是分块清单
+	if isChunked(info) {
+		return fmt.Errorf("分块存储的文件不能分享: %s", path)
+	}
+	return nil
+}
+
+// availableShareLink 读取仍可访问的分享链接，不可访问时返回HTTP状态码和原因；调用方需持有 a.shareMu
+func (a *App) availableShareLink(id string) (ShareLink, int, string) {
+	link, exists := a.shareLinks[id]
+	if !exists {
+		return ShareLink{}, http.StatusNotFound, "分享链接不存在或已被撤销"
+	}
+	if !link.ExpiresAt.IsZero() && time.Now().After(link.ExpiresAt) {
+		return ShareLink{}, http.StatusGone, "分享链接已过期"
+	}
+	if link.MaxViews > 0 && link.Views >= link.MaxViews {
+		return ShareLink{}, http.StatusGone, "分享链接的访问次数已用完"
+	}
+	return link, 0, ""
+}
+
+// reserveSharePasswordAttempt 验证密码前登记一次尝试，返回是否允许验证，调用方需持有 a.shareMu
+// 正在验证的次数和失败次数一起受上限限制，每个链接并发的密码哈希计算不超过上限
+func (a *App) reserveSharePasswordAttempt(id string) bool {
+	attempt := a.shareAttempts[id]
+	if time.Now().Before(attempt.blockedUntil) || attempt.failures+attempt.pending >= sharePasswordMaxFailures {
+		return false
+	}
+	attempt.pending++
+	a.shareAttempts[id] = attempt
+	return true
+}
+
+// finishSharePasswordAttempt 结束登记的尝试，密码错误时计入失败次数，达到上限后暂停验证，调用方需持有 a.shareMu
+// 密码正确时清除失败次数，其他请求触发的暂停仍然有效
+func (a *App) finishSharePasswordAttempt(id string, ok bool) {
+	attempt := a.shareAttempts[id]
+	// 验证期间链接被撤销时记录已被清除
+	if attempt.pending > 0 {
+		attempt.pending--
+	}
+	if ok {
+		attempt.failures = 0
+	} else {
+		attempt.failures++
+		if attempt.failures >= sharePasswordMaxFailures {
+			attempt.failures = 0
+			attempt.blockedUntil = time.Now().Add(sharePasswordLockout)
+		}
+	}
+
+	if attempt.failures == 0 && attempt.pending == 0 && !time.Now().Before(attempt.blockedUntil) {
+		delete(a.shareAttempts, id)
+		return
+	}
+	a.shareAttempts[id] = attempt
+}
+
+// writeSharePasswordForm 输出输入分享密码的页面
+func writeSharePasswordForm(w http.ResponseWriter, link ShareLink, status int, wrong bool) {
+	message := ""
+	if wrong {
+		message = "<p style=\"color:#c00\">密码错误</p>"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>ACloud 文件分享</title></head>
+<body style="font-family:sans-serif;margin:40px">
+<h3>%s</h3>
+<p>该分享链接需要密码</p>%s
+<form method="post"><input type="password" name="password" autofocus> <button type="submit">下载</button></form>
+</body></html>`, html.EscapeString(filepath.Base(link.Path)), message)
+}
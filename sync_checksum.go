@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// checksumMetadataKey 上传时写入对象用户元数据的内容校验和（X-Amz-Meta-Sha256）
+const checksumMetadataKey = "Sha256"
+
+// calculateSHA256 计算文件的SHA-256哈希值
+func calculateSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("计算哈希值失败: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// calculateChecksums 读取一次文件，同时计算MD5和SHA-256
+func calculateChecksums(filePath string) (string, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	md5Hash, shaHash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, shaHash), file); err != nil {
+		return "", "", fmt.Errorf("计算哈希值失败: %v", err)
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(shaHash.Sum(nil)), nil
+}
+
+// checksumMetadata 生成上传时写入的用户元数据
+func checksumMetadata(checksum string) map[string]string {
+	return map[string]string{checksumMetadataKey: checksum}
+}
+
+// objectChecksum 从对象用户元数据中读取内容校验和，没有时返回空字符串
+func objectChecksum(metadata map[string]string) string {
+	return strings.ToLower(metadataValue(metadata, checksumMetadataKey))
+}
+
+// singlePartETag 普通上传的对象ETag就是内容MD5；分片上传的ETag带有“-分片数”后缀，不能用于比较
+func singlePartETag(etag string) (string, bool) {
+	etag = strings.Trim(etag, "\"")
+	if etag == "" || strings.Contains(etag, "-") {
+		return "", false
+	}
+	return strings.ToLower(etag), true
+}
+
+// sameContent 判断本地文件与远程对象的内容是否一致，不下载对象内容
+// 优先使用对象元数据中的SHA-256，其次使用单分片ETag；都没有时通过 StatObject 读取元数据，仍无法判断则视为不一致
+// localMD5 为空时会重新计算
+func (a *App) sameContent(ctx context.Context, localPath, localMD5 string, remote *MinioFileInfo) (bool, error) {
+	// 加密对象的ETag由密文计算，只能比较带密钥的校验和
+	enc, err := a.cipherForKey(remote.Path)
+	if err != nil {
+		return false, err
+	}
+
+	if remote.SHA256 == "" {
+		// 压缩对象和分块清单的ETag同样不是本地文件的MD5
+		_, compressed := a.compressionRuleForKey(remote.Path)
+		_, chunked := a.ruleForKey(remote.Path, func(rule SyncRule) bool { return rule.Chunking.Enabled })
+		if etag, ok := singlePartETag(remote.ETag); ok && enc == nil && !compressed && !chunked {
+			if localMD5 == "" {
+				hash, err := calculateMD5(localPath)
+				if err != nil {
+					return false, err
+				}
+				localMD5 = hash
+			}
+			return etag == localMD5, nil
+		}
+
+		// 列表结果不包含用户元数据，单独查询
+		if a.minioClient == nil {
+			return false, fmt.Errorf("MinIO客户端未初始化")
+		}
+		info, err := a.statObject(ctx, remote.Path, "")
+		if err != nil {
+			return false, fmt.Errorf("获取远程文件信息失败: %v", err)
+		}
+		remote.SHA256 = objectChecksum(info.UserMetadata)
+		if remote.SHA256 == "" {
+			return false, nil
+		}
+	}
+
+	checksum, err := calculateSHA256(localPath)
+	if err != nil {
+		return false, err
+	}
+	// 加密规则只接受密文对象，目录中残留的明文对象会被重新加密上传
+	if enc != nil {
+		return enc.checksum(checksum) == remote.SHA256, nil
+	}
+	return checksum == remote.SHA256, nil
+}
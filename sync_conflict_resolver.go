@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,17 +19,18 @@ const (
 )
 
 // detectConflicts 检测同步冲突
-func (a *App) detectConflicts(localPath, remotePath string) ([]ConflictFile, error) {
+// 基于同步索引做三方比较，只有本地和远程自上次同步后都发生变化且内容不同的文件才视为冲突
+func (a *App) detectConflicts(ctx context.Context, config SyncConfig) ([]ConflictFile, error) {
 	var conflicts []ConflictFile
 	
 	// 获取本地文件列表
-	localFiles, err := getAllFiles(localPath)
+	localFiles, err := getAllFiles(config.LocalPath)
 	if err != nil {
 		return nil, fmt.Errorf("获取本地文件列表失败: %v", err)
 	}
 	
 	// 获取远程文件列表
-	remoteFiles, err := a.ListMinioFiles(remotePath)
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
 	if err != nil {
 		return nil, fmt.Errorf("获取远程文件列表失败: %v", err)
 	}
@@ -41,69 +43,110 @@ func (a *App) detectConflicts(localPath, remotePath string) ([]ConflictFile, err
 		}
 	}
 	
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return nil, err
+	}
+	defer a.saveSyncIndexQuietly(index)
+	
 	// 检查每个本地文件
 	for _, localFile := range localFiles {
 		// 计算相对路径
-		relPath, err := filepath.Rel(localPath, localFile)
+		relPath, err := syncRelPath(config.LocalPath, localFile)
 		if err != nil {
 			return nil, fmt.Errorf("计算相对路径失败: %v", err)
 		}
 		
-		// 转换为远程路径
-		remotePath := filepath.Join(remotePath, relPath)
-		remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-		
 		// 检查远程文件是否存在
-		remoteFile, exists := remoteFileMap[remotePath]
+		remoteFile, exists := remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)]
 		if !exists {
 			continue // 远程文件不存在，不是冲突
 		}
 		
-		// 获取本地文件的修改时间
-		localModTime, err := getFileModTime(localFile)
+		// 获取本地文件信息
+		localInfo, err := os.Stat(localFile)
 		if err != nil {
-			return nil, fmt.Errorf("获取本地文件修改时间失败: %v", err)
+			return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
 		}
 		
-		// 获取远程文件的修改时间
-		remoteModTime := remoteFile.LastModified
+		// 三方比较，只有两端都有变化才可能是冲突
+		change, err := index.classify(relPath, localFile, localInfo, &remoteFile)
+		if err != nil {
+			return nil, fmt.Errorf("比较文件状态失败: %v", err)
+		}
+		if change != SyncChangeBoth {
+			continue
+		}
 		
-		// 检查修改时间
-		if localModTime.After(a.lastSyncTime) && remoteModTime.After(a.lastSyncTime) {
-			// 本地和远程都有修改，这是一个潜在冲突
-			
-			// 计算本地文件的校验和
-			localChecksum, err := calculateMD5(localFile)
-			if err != nil {
-				return nil, fmt.Errorf("计算本地文件校验和失败: %v", err)
+		// 计算本地文件的校验和
+		localChecksum, err := calculateMD5(localFile)
+		if err != nil {
+			return nil, fmt.Errorf("计算本地文件校验和失败: %v", err)
+		}
+		
+		// 通过对象元数据或ETag比较内容，无需下载远程文件
+		same, err := a.sameContent(ctx, localFile, localChecksum, &remoteFile)
+		if err != nil {
+			return nil, fmt.Errorf("比较远程文件校验和失败: %v", err)
+		}
+		
+		// 如果内容相同，不是冲突，两端已一致
+		if same {
+			index.record(relPath, localInfo, localChecksum, remoteFile.ETag, remoteFile.VersionID)
+			continue
+		}
+		
+		conflicts = append(conflicts, ConflictFile{
+			Path:          localFile,
+			LocalModTime:  localInfo.ModTime(),
+			RemoteModTime: remoteFile.LastModified,
+			Resolution:    "pending",
+		})
+	}
+	
+	return conflicts, nil
+}
+
+// handleRuleConflicts 检测规则的冲突并加入冲突列表，按默认解决方式自动处理
+func (a *App) handleRuleConflicts(ctx context.Context, config SyncConfig, status *SyncStatus) {
+	conflicts, err := a.detectConflicts(ctx, config)
+	if err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("检测冲突失败: %v", err))
+		return
+	}
+	if len(conflicts) == 0 {
+		return
+	}
+	
+	// 添加到冲突列表，同一文件只保留最新的一条
+	for _, conflict := range conflicts {
+		replaced := false
+		for i, existing := range a.conflictFiles {
+			if existing.Path == conflict.Path {
+				a.conflictFiles[i] = conflict
+				replaced = true
+				break
 			}
-			
-			// 下载远程文件并计算校验和
-			remoteData, err := a.DownloadFileFromMinio(remotePath)
-			if err != nil {
-				return nil, fmt.Errorf("下载远程文件失败: %v", err)
+		}
+		if !replaced {
+			a.conflictFiles = append(a.conflictFiles, conflict)
+		}
+	}
+	status.ConflictCount += len(conflicts)
+	
+	// 如果有冲突且默认解决方式不是询问，自动解决冲突
+	if a.defaultConflictResolution != ConflictResolutionAsk {
+		for _, conflict := range conflicts {
+			if ctx.Err() != nil {
+				return
 			}
-			
-			remoteChecksum, err := calculateMD5FromBytes(remoteData)
+			err := a.ResolveConflict(conflict.Path, a.defaultConflictResolution)
 			if err != nil {
-				return nil, fmt.Errorf("计算远程文件校验和失败: %v", err)
-			}
-			
-			// 如果校验和相同，不是冲突
-			if localChecksum == remoteChecksum {
-				continue
+				status.Errors = append(status.Errors, fmt.Sprintf("解决冲突失败: %v", err))
 			}
-			
-			conflicts = append(conflicts, ConflictFile{
-				Path:          localFile,
-				LocalModTime:  localModTime,
-				RemoteModTime: remoteModTime,
-				Resolution:    "pending",
-			})
 		}
 	}
-	
-	return conflicts, nil
 }
 
 // ResolveConflict 解决单个冲突
@@ -155,13 +198,8 @@ func (a *App) ResolveConflict(path string, resolution string) error {
 	case ConflictResolutionRemote:
 		// 使用远程文件，下载到本地
 		remotePath := a.getRemotePathForLocalFile(conflict.Path)
-		data, err := a.DownloadFileFromMinio(remotePath)
-		if err != nil {
-			return fmt.Errorf("下载文件失败: %v", err)
-		}
-		err = os.WriteFile(conflict.Path, data, 0644)
-		if err != nil {
-			return fmt.Errorf("写入本地文件失败: %v", err)
+		if _, err := a.downloadFileToPath(context.Background(), remotePath, conflict.Path); err != nil {
+			return err
 		}
 		conflict.Resolution = ConflictResolutionRemote
 		
@@ -179,21 +217,25 @@ func (a *App) ResolveConflict(path string, resolution string) error {
 			return fmt.Errorf("重命名本地文件失败: %v", err)
 		}
 		
-		// 下载远程文件到原路径
+		// 下载远程文件到原路径，失败时恢复本地文件
 		remotePath := a.getRemotePathForLocalFile(conflict.Path)
-		data, err := a.DownloadFileFromMinio(remotePath)
-		if err != nil {
-			return fmt.Errorf("下载文件失败: %v", err)
-		}
-		err = os.WriteFile(conflict.Path, data, 0644)
-		if err != nil {
-			return fmt.Errorf("写入本地文件失败: %v", err)
+		if _, err := a.downloadFileToPath(context.Background(), remotePath, conflict.Path); err != nil {
+			if restoreErr := os.Rename(newLocalPath, conflict.Path); restoreErr != nil {
+				return fmt.Errorf("%v; 恢复本地文件失败: %v", err, restoreErr)
+			}
+			return err
 		}
 		conflict.Resolution = ConflictResolutionBoth
 		
 	case ConflictResolutionSkip:
 		// 跳过，不做任何操作
 		conflict.Resolution = ConflictResolutionSkip
+		return nil
+	}
+	
+	// 两端已一致，更新同步索引
+	if err := a.recordResolvedFile(conflict.Path); err != nil {
+		fmt.Printf("更新同步索引失败: %v\n", err)
 	}
 	
 	return nil
@@ -238,21 +280,25 @@ func (a *App) ResolveAllConflicts(resolution string) error {
 	return nil
 }
 
+// findSyncRuleForLocalFile 查找本地文件所属的同步规则
+func (a *App) findSyncRuleForLocalFile(localPath string) (SyncRule, bool) {
+	for _, rule := range a.syncRules {
+		if strings.HasPrefix(localPath, rule.LocalPath) {
+			return rule, true
+		}
+	}
+	return SyncRule{}, false
+}
+
 // getRemotePathForLocalFile 获取本地文件对应的远程路径
 func (a *App) getRemotePathForLocalFile(localPath string) string {
 	// 查找匹配的同步规则
-	for _, rule := range a.syncRules {
-		if strings.HasPrefix(localPath, rule.LocalPath) {
-			// 计算相对路径
-			relPath, err := filepath.Rel(rule.LocalPath, localPath)
-			if err != nil {
-				continue
-			}
-			
+	if rule, ok := a.findSyncRuleForLocalFile(localPath); ok {
+		// 计算相对路径
+		relPath, err := syncRelPath(rule.LocalPath, localPath)
+		if err == nil {
 			// 转换为远程路径
-			remotePath := filepath.Join(rule.RemotePath, relPath)
-			remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-			return remotePath
+			return a.syncRemoteKey(rule.RemotePath, relPath)
 		}
 	}
 	
@@ -260,6 +306,44 @@ func (a *App) getRemotePathForLocalFile(localPath string) string {
 	return filepath.Base(localPath)
 }
 
+// recordResolvedFile 冲突解决后，将本地文件和远程对象的当前状态写入同步索引
+func (a *App) recordResolvedFile(localPath string) error {
+	rule, ok := a.findSyncRuleForLocalFile(localPath)
+	if !ok {
+		return nil
+	}
+	
+	config := syncConfigFromRule(rule)
+	
+	relPath, err := syncRelPath(rule.LocalPath, localPath)
+	if err != nil {
+		return fmt.Errorf("计算相对路径失败: %v", err)
+	}
+	
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+	
+	localHash, err := calculateMD5(localPath)
+	if err != nil {
+		return err
+	}
+	
+	remoteInfo, err := a.GetMinioFileInfo(a.syncRemoteKey(rule.RemotePath, relPath))
+	if err != nil {
+		return fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+	
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	index.record(relPath, localInfo, localHash, remoteInfo.ETag, remoteInfo.VersionID)
+	
+	return a.saveSyncIndex(index)
+}
+
 // GetConflictCount 获取冲突文件数量
 func (a *App) GetConflictCount() int {
 	return len(a.conflictFiles)
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// syncPauser 同步暂停控制，暂停期间传输队列保持不变，工作协程在开始下一个任务前等待
+type syncPauser struct {
+	mu       sync.Mutex
+	paused   bool
+	resumeCh chan struct{} // 恢复时关闭
+}
+
+// pause 暂停同步，已暂停时返回false
+func (p *syncPauser) pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused {
+		return false
+	}
+	p.paused = true
+	p.resumeCh = make(chan struct{})
+	return true
+}
+
+// resume 恢复同步，未暂停时返回false
+func (p *syncPauser) resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		return false
+	}
+	p.paused = false
+	close(p.resumeCh)
+	return true
+}
+
+// isPaused 是否处于暂停状态
+func (p *syncPauser) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// wait 暂停时等待恢复；同步被取消时返回上下文错误
+func (p *syncPauser) wait(ctx context.Context) error {
+	p.mu.Lock()
+	paused, resumeCh := p.paused, p.resumeCh
+	p.mu.Unlock()
+
+	if paused {
+		select {
+		case <-resumeCh:
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err()
+}
+
+// syncCheckpoint 同步过程中的检查点：暂停时等待恢复，已取消时返回错误
+func (a *App) syncCheckpoint(ctx context.Context) error {
+	return a.syncPause.wait(ctx)
+}
+
+// beginSyncRun 登记一次正在执行的同步，返回可被 StopSync 取消的上下文
+// 已有同步在执行时返回false
+func (a *App) beginSyncRun(parent context.Context) (context.Context, bool) {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+
+	if a.runCancel != nil {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(parent)
+	a.runCancel = cancel
+	return ctx, true
+}
+
+// endSyncRun 结束当前同步的登记
+func (a *App) endSyncRun() {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+
+	if a.runCancel != nil {
+		a.runCancel()
+		a.runCancel = nil
+	}
+}
+
+// isSyncActive 是否有同步正在执行
+func (a *App) isSyncActive() bool {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	return a.runCancel != nil
+}
+
+// cancelSync 停止同步服务并取消正在执行的同步，进行中的传输会立即中断
+func (a *App) cancelSync() {
+	a.syncMu.Lock()
+	if a.serviceCancel != nil {
+		a.serviceCancel()
+		a.serviceCancel = nil
+		a.serviceCtx = nil
+	}
+	if a.runCancel != nil {
+		a.runCancel()
+	}
+	a.syncMu.Unlock()
+
+	// 停止文件监控
+	a.stopFileWatcher()
+	a.stopRemoteWatcher()
+
+	// 清除暂停状态，等待中的工作协程随上下文取消退出
+	a.syncPause.resume()
+}
+
+// PauseSync 暂停正在执行的同步，当前文件传输完成后不再开始新的传输
+func (a *App) PauseSync() error {
+	if !a.isSyncActive() && !a.syncRunning {
+		return fmt.Errorf("同步服务未在运行")
+	}
+	if !a.syncPause.pause() {
+		return fmt.Errorf("同步已暂停")
+	}
+
+	a.setSyncProgressStatus("paused")
+	fmt.Println("同步已暂停")
+	return nil
+}
+
+// ResumeSync 恢复已暂停的同步
+func (a *App) ResumeSync() error {
+	if !a.syncPause.resume() {
+		return fmt.Errorf("同步未暂停")
+	}
+
+	if a.isSyncActive() {
+		a.setSyncProgressStatus("running")
+	} else {
+		a.setSyncProgressStatus("idle")
+	}
+	fmt.Println("同步已恢复")
+	return nil
+}
+
+// IsSyncPaused 同步是否已暂停
+func (a *App) IsSyncPaused() bool {
+	return a.syncPause.isPaused()
+}
+
+// setSyncProgressStatus 更新同步进度状态并通知前端
+func (a *App) setSyncProgressStatus(status string) {
+	syncProgressMu.Lock()
+	syncProgress.Status = status
+	progress := syncProgress
+	syncProgressMu.Unlock()
+
+	// 命令行模式下没有前端上下文，只更新进度数据
+	if a.ctx == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, "sync-progress-update", progress)
+}
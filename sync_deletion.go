@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// defaultMaxDeletePercent 默认的删除保护阈值（百分比）
+const defaultMaxDeletePercent = 50
+
+// DeletionPlan 删除传播计划
+type DeletionPlan struct {
+	RemoteDeletes []string `json:"remoteDeletes"` // 本地已删除、需要从远程删除的相对路径
+	LocalDeletes  []string `json:"localDeletes"`  // 远程已删除、需要从本地删除的相对路径
+	TrackedFiles  int      `json:"trackedFiles"`  // 上次同步时记录的文件数
+}
+
+// Total 计划删除的文件总数
+func (p DeletionPlan) Total() int {
+	return len(p.RemoteDeletes) + len(p.LocalDeletes)
+}
+
+// effectiveMaxDeletePercent 获取实际生效的删除保护阈值
+func effectiveMaxDeletePercent(maxPercent int) int {
+	if maxPercent <= 0 {
+		return defaultMaxDeletePercent
+	}
+	return maxPercent
+}
+
+// exceedsThreshold 检查计划删除数量是否超过删除保护阈值
+func (p DeletionPlan) exceedsThreshold(maxPercent int) bool {
+	maxPercent = effectiveMaxDeletePercent(maxPercent)
+	if maxPercent >= 100 || p.TrackedFiles == 0 {
+		return false
+	}
+	return p.Total()*100 > p.TrackedFiles*maxPercent
+}
+
+// planDeletions 根据同步索引计算需要传播的删除
+// 只有上次同步时两端都存在、此后一端被删除而另一端未修改的文件才会被删除；
+// 另一端已修改的文件从索引中移除，随后按新文件重新同步
+func (a *App) planDeletions(config SyncConfig, index *SyncIndex, localFiles []string, remoteFileMap map[string]MinioFileInfo) (DeletionPlan, error) {
+	plan := DeletionPlan{TrackedFiles: len(index.Entries)}
+
+	// 创建本地文件集合
+	localSet := make(map[string]string)
+	for _, file := range localFiles {
+		relPath, err := syncRelPath(config.LocalPath, file)
+		if err != nil {
+			return plan, fmt.Errorf("计算相对路径失败: %v", err)
+		}
+		localSet[relPath] = file
+	}
+
+	for relPath := range index.Entries {
+		localFile, localExists := localSet[relPath]
+		remoteFile, remoteExists := remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)]
+
+		switch {
+		case !localExists && !remoteExists:
+			// 两端都已删除
+			index.forget(relPath)
+
+		case !localExists && remoteExists:
+			// 本地已删除
+			if index.remoteChanged(relPath, remoteFile) {
+				index.forget(relPath)
+			} else {
+				plan.RemoteDeletes = append(plan.RemoteDeletes, relPath)
+			}
+
+		case localExists && !remoteExists:
+			// 远程已删除
+			localInfo, err := os.Stat(localFile)
+			if err != nil {
+				return plan, fmt.Errorf("获取本地文件信息失败: %v", err)
+			}
+			changed, _, err := index.localChanged(relPath, localFile, localInfo)
+			if err != nil {
+				return plan, fmt.Errorf("比较文件状态失败: %v", err)
+			}
+			if changed {
+				index.forget(relPath)
+			} else {
+				plan.LocalDeletes = append(plan.LocalDeletes, relPath)
+			}
+		}
+	}
+
+	sort.Strings(plan.RemoteDeletes)
+	sort.Strings(plan.LocalDeletes)
+	return plan, nil
+}
+
+// propagateDeletions 为双向同步规则传播两端的删除操作
+func (a *App) propagateDeletions(ctx context.Context, config SyncConfig, status *SyncStatus) error {
+	// 获取本地文件列表
+	localFiles, err := getAllFiles(config.LocalPath)
+	if err != nil {
+		return fmt.Errorf("获取本地文件列表失败: %v", err)
+	}
+
+	// 获取远程文件列表
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
+	if err != nil {
+		return fmt.Errorf("获取远程文件列表失败: %v", err)
+	}
+
+	// 创建远程文件映射，用于快速查找
+	remoteFileMap := make(map[string]MinioFileInfo)
+	for _, file := range remoteFiles {
+		if !file.IsDir {
+			remoteFileMap[file.Path] = file
+		}
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	plan, err := a.planDeletions(config, index, localFiles, remoteFileMap)
+	if err != nil {
+		return err
+	}
+	if plan.Total() == 0 {
+		return nil
+	}
+
+	// 删除保护：删除比例过高时中止本次同步，避免误删
+	if plan.exceedsThreshold(config.MaxDeletePercent) {
+		return fmt.Errorf("删除数量超过保护阈值，已中止同步: 将删除 %d/%d 个文件，阈值为 %d%%",
+			plan.Total(), plan.TrackedFiles, effectiveMaxDeletePercent(config.MaxDeletePercent))
+	}
+
+	// 删除远程文件
+	for _, relPath := range plan.RemoteDeletes {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+		remotePath := a.syncRemoteKey(config.RemotePath, relPath)
+		fmt.Printf("删除远程文件: %s\n", remotePath)
+		if err := a.DeleteFileFromMinio(remotePath); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("删除远程文件失败: %v", err))
+			continue
+		}
+		index.forget(relPath)
+	}
+
+	// 删除本地文件
+	for _, relPath := range plan.LocalDeletes {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+		localPath := filepath.Join(config.LocalPath, filepath.FromSlash(relPath))
+		fmt.Printf("删除本地文件: %s\n", localPath)
+		if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
+			status.Errors = append(status.Errors, fmt.Sprintf("删除本地文件失败: %v", err))
+			continue
+		}
+		removeEmptyParents(filepath.Dir(localPath), config.LocalPath)
+		index.forget(relPath)
+	}
+
+	return nil
+}
+
+// removeEmptyParents 自下而上删除空目录，直到同步根目录为止
+func removeEmptyParents(dir, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,636 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// encryptionDescriptorName 加密规则远程根目录下的密钥描述对象，记录密钥派生参数，同步时跳过
+	encryptionDescriptorName = ".acloud-encryption.json"
+	// encryptionScheme 加密对象的格式标识
+	encryptionScheme = "aes-256-gcm-v1"
+	// encryptionMagic 加密对象内容的文件头
+	encryptionMagic = "ACE1"
+	// encryptionSaltSize 每个对象随机生成的盐长度，用于派生该对象的内容密钥
+	encryptionSaltSize = 32
+	// encryptionChunkSize 明文分块大小，每块单独认证，下载时可流式解密
+	encryptionChunkSize = 64 * 1024
+)
+
+// 加密对象写入用户元数据的信息；Sha256 元数据保存的是带密钥的校验和，不暴露明文哈希
+const (
+	encryptionMetadataKey = "Encryption" // 加密格式
+	keyCheckMetadataKey   = "Key-Check"  // 密钥标识，用于识别错误的密码
+	plainSizeMetadataKey  = "Plain-Size" // 明文大小
+)
+
+// RuleEncryption 同步规则的客户端加密设置
+type RuleEncryption struct {
+	Enabled      bool `json:"enabled"`
+	EncryptNames bool `json:"encryptNames"` // 同时加密对象键中的文件名
+}
+
+// encryptionDescriptor 密钥描述，保存在远程，其他设备用相同的密码即可派生出相同的密钥
+type encryptionDescriptor struct {
+	Version      int       `json:"version"`
+	KDF          string    `json:"kdf"`
+	Time         uint32    `json:"time"`
+	Memory       uint32    `json:"memory"` // KiB
+	Threads      uint8     `json:"threads"`
+	Salt         string    `json:"salt"`
+	KeyCheck     string    `json:"keyCheck"`
+	EncryptNames bool      `json:"encryptNames"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ruleKeyFile 记住密码时保存在密钥存储中的规则密钥
+type ruleKeyFile struct {
+	Key          string `json:"key"`
+	KeyCheck     string `json:"keyCheck"`
+	EncryptNames bool   `json:"encryptNames"`
+}
+
+// ruleCipher 已解锁的加密规则，持有由主密钥派生的各用途子密钥
+type ruleCipher struct {
+	prefix       string // 规则远程根路径对应的对象前缀
+	keyCheck     string
+	encryptNames bool
+	master       []byte
+	contentKey   []byte
+	nameKey      []byte
+	nameMACKey   []byte
+	checksumKey  []byte
+}
+
+// deriveSubkey 使用HKDF从主密钥派生指定用途的子密钥
+func deriveSubkey(master []byte, salt []byte, purpose string) []byte {
+	key := make([]byte, 32)
+	io.ReadFull(hkdf.New(sha256.New, master, salt, []byte(purpose)), key)
+	return key
+}
+
+// keyCheckFor 计算主密钥的标识，不能由标识反推密钥
+func keyCheckFor(master []byte) string {
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("acloud key check"))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// newRuleCipher 根据主密钥创建规则加密器
+func newRuleCipher(master []byte, prefix string, encryptNames bool) *ruleCipher {
+	return &ruleCipher{
+		prefix:       prefix,
+		keyCheck:     keyCheckFor(master),
+		encryptNames: encryptNames,
+		master:       master,
+		contentKey:   deriveSubkey(master, nil, "acloud content"),
+		nameKey:      deriveSubkey(master, nil, "acloud name"),
+		nameMACKey:   deriveSubkey(master, nil, "acloud name mac"),
+		checksumKey:  deriveSubkey(master, nil, "acloud checksum"),
+	}
+}
+
+// deriveMasterKey 按描述中的参数由密码派生主密钥
+func deriveMasterKey(passphrase string, desc *encryptionDescriptor) ([]byte, error) {
+	salt, err := hex.DecodeString(desc.Salt)
+	if err != nil || desc.KDF != "argon2id" {
+		return nil, fmt.Errorf("无法识别的密钥描述")
+	}
+	return argon2.IDKey([]byte(passphrase), salt, desc.Time, desc.Memory, desc.Threads, 32), nil
+}
+
+// checksum 计算写入元数据的带密钥校验和
+func (c *ruleCipher) checksum(sha string) string {
+	mac := hmac.New(sha256.New, c.checksumKey)
+	mac.Write([]byte(sha))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// encryptedSize 明文大小对应的加密对象大小
+func (c *ruleCipher) encryptedSize(size int64) int64 {
+	chunks := (size + encryptionChunkSize - 1) / encryptionChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(len(encryptionMagic)+encryptionSaltSize) + size + chunks*16
+}
+
+// objectMetadata 在文件元数据基础上生成加密对象的元数据
+func (c *ruleCipher) objectMetadata(metadata map[string]string, checksum string, size int64) map[string]string {
+	metadata[checksumMetadataKey] = c.checksum(checksum)
+	metadata[encryptionMetadataKey] = encryptionScheme
+	metadata[keyCheckMetadataKey] = c.keyCheck
+	// 压缩后的对象已记录压缩前的大小
+	if _, exists := metadata[plainSizeMetadataKey]; !exists {
+		metadata[plainSizeMetadataKey] = strconv.FormatInt(size, 10)
+	}
+	return metadata
+}
+
+// chunkNonce 分块的随机数：前11字节为块序号，最后1字节标记最后一块，防止截断和重排
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// fileAEAD 由对象的随机盐派生该对象的内容密钥
+func (c *ruleCipher) fileAEAD(salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveSubkey(c.contentKey, salt, "acloud file"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptingReader 边读取明文边输出加密内容
+type encryptingReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte
+	out     []byte
+	pending []byte
+	done    bool
+}
+
+// encryptReader 返回加密后的内容，输出大小为 encryptedSize(明文大小)
+func (c *ruleCipher) encryptReader(r io.Reader) (io.Reader, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	aead, err := c.fileAEAD(salt)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %v", err)
+	}
+	return &encryptingReader{
+		src:     bufio.NewReaderSize(r, encryptionChunkSize),
+		aead:    aead,
+		plain:   make([]byte, encryptionChunkSize),
+		out:     make([]byte, 0, encryptionChunkSize+aead.Overhead()),
+		pending: append([]byte(encryptionMagic), salt...),
+	}, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next 读取并加密下一块明文
+func (r *encryptingReader) next() error {
+	n, err := io.ReadFull(r.src, r.plain)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.pending = r.aead.Seal(r.out[:0], chunkNonce(r.counter, last), r.plain[:n], nil)
+	r.counter++
+	r.done = last
+	return nil
+}
+
+// decryptingReader 边读取加密内容边输出明文，任何一块认证失败都返回错误
+type decryptingReader struct {
+	enc     *ruleCipher
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	sealed  []byte
+	plain   []byte
+	pending []byte
+	done    bool
+}
+
+// decryptReader 返回解密后的内容
+func (c *ruleCipher) decryptReader(r io.Reader) io.Reader {
+	return &decryptingReader{enc: c, src: bufio.NewReaderSize(r, encryptionChunkSize+16)}
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next 读取并解密下一块内容，第一次调用时先读取文件头
+func (r *decryptingReader) next() error {
+	if r.aead == nil {
+		header := make([]byte, len(encryptionMagic)+encryptionSaltSize)
+		if _, err := io.ReadFull(r.src, header); err != nil {
+			return fmt.Errorf("解密失败: 加密内容不完整")
+		}
+		if string(header[:len(encryptionMagic)]) != encryptionMagic {
+			return fmt.Errorf("解密失败: 无法识别的加密格式")
+		}
+		aead, err := r.enc.fileAEAD(header[len(encryptionMagic):])
+		if err != nil {
+			return fmt.Errorf("创建解密器失败: %v", err)
+		}
+		r.aead = aead
+		r.sealed = make([]byte, encryptionChunkSize+aead.Overhead())
+		r.plain = make([]byte, 0, encryptionChunkSize)
+	}
+
+	n, err := io.ReadFull(r.src, r.sealed)
+	last := false
+	switch {
+	case err == io.EOF:
+		// 最后一块必须带有结束标记，没有读到说明内容被截断
+		return fmt.Errorf("解密失败: 加密内容不完整")
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.plain[:0], chunkNonce(r.counter, last), r.sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("解密失败: 内容已损坏或密钥不正确")
+	}
+	r.pending = plain
+	r.counter++
+	r.done = last
+	return nil
+}
+
+// encryptName 确定性地加密单个文件名：以明文的HMAC作为初始向量，相同文件名总是得到相同的密文
+func (c *ruleCipher) encryptName(name string) string {
+	mac := hmac.New(sha256.New, c.nameMACKey)
+	mac.Write([]byte(name))
+	iv := mac.Sum(nil)[:aes.BlockSize]
+
+	block, _ := aes.NewCipher(c.nameKey)
+	out := make([]byte, aes.BlockSize+len(name))
+	copy(out, iv)
+	cipher.NewCTR(block, iv).XORKeyStream(out[aes.BlockSize:], []byte(name))
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+// decryptName 解密文件名并校验
+func (c *ruleCipher) decryptName(encoded string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aes.BlockSize {
+		return "", fmt.Errorf("无效的加密文件名: %s", encoded)
+	}
+	iv := data[:aes.BlockSize]
+
+	block, _ := aes.NewCipher(c.nameKey)
+	name := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCTR(block, iv).XORKeyStream(name, data[aes.BlockSize:])
+
+	mac := hmac.New(sha256.New, c.nameMACKey)
+	mac.Write(name)
+	if !hmac.Equal(mac.Sum(nil)[:aes.BlockSize], iv) {
+		return "", fmt.Errorf("无效的加密文件名: %s", encoded)
+	}
+	return string(name), nil
+}
+
+// encryptPath 逐级加密相对路径
+func (c *ruleCipher) encryptPath(relPath string) string {
+	parts := strings.Split(relPath, "/")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = c.encryptName(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// decryptPath 逐级解密相对路径
+func (c *ruleCipher) decryptPath(relPath string) (string, error) {
+	parts := strings.Split(relPath, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		name, err := c.decryptName(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = name
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// EnableRuleEncryption 为同步规则启用客户端加密
+// 远程目录已有其他设备创建的密钥描述时使用相同的设置并校验密码；否则新建描述，并要求远程目录为空，
+// 已有的明文对象不会被重新加密，留在目录中会让规则同时包含明文和密文
+func (a *App) EnableRuleEncryption(ruleID, passphrase string, encryptNames, remember bool) error {
+	if passphrase == "" {
+		return fmt.Errorf("密码不能为空")
+	}
+	if a.minioClient == nil {
+		return fmt.Errorf("MinIO客户端未初始化")
+	}
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+	if rule.Encryption.Enabled {
+		return fmt.Errorf("规则 '%s' 已启用加密", rule.Name)
+	}
+
+	ctx := context.Background()
+	desc, err := a.loadEncryptionDescriptor(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	var master []byte
+	if desc != nil {
+		master, err = deriveMasterKey(passphrase, desc)
+		if err != nil {
+			return err
+		}
+		if keyCheckFor(master) != desc.KeyCheck {
+			return fmt.Errorf("密码与远程目录已有的加密设置不匹配")
+		}
+	} else {
+		files, err := a.ListMinioFiles(rule.RemotePath)
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			return fmt.Errorf("远程目录已有 %d 个未加密的对象，启用加密需要使用空目录", len(files))
+		}
+
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("生成随机数失败: %v", err)
+		}
+		desc = &encryptionDescriptor{
+			Version:      1,
+			KDF:          "argon2id",
+			Time:         3,
+			Memory:       64 * 1024,
+			Threads:      4,
+			Salt:         hex.EncodeToString(salt),
+			EncryptNames: encryptNames,
+			CreatedAt:    time.Now(),
+		}
+		if master, err = deriveMasterKey(passphrase, desc); err != nil {
+			return err
+		}
+		desc.KeyCheck = keyCheckFor(master)
+		if err := a.saveEncryptionDescriptor(ctx, rule, desc); err != nil {
+			return err
+		}
+	}
+
+	if err := a.unlockRule(rule, master, desc.EncryptNames, remember); err != nil {
+		return err
+	}
+	rule.Encryption = RuleEncryption{Enabled: true, EncryptNames: desc.EncryptNames}
+	return a.UpdateSyncRule(rule)
+}
+
+// UnlockRuleEncryption 输入密码解锁加密规则，remember 为 true 时将密钥保存在本机，重启后无需再次输入
+func (a *App) UnlockRuleEncryption(ruleID, passphrase string, remember bool) error {
+	if a.minioClient == nil {
+		return fmt.Errorf("MinIO客户端未初始化")
+	}
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return err
+	}
+	if !rule.Encryption.Enabled {
+		return fmt.Errorf("规则 '%s' 未启用加密", rule.Name)
+	}
+
+	desc, err := a.loadEncryptionDescriptor(context.Background(), rule)
+	if err != nil {
+		return err
+	}
+	if desc == nil {
+		return fmt.Errorf("远程目录中没有密钥描述: %s", a.encryptionDescriptorKey(rule))
+	}
+	master, err := deriveMasterKey(passphrase, desc)
+	if err != nil {
+		return err
+	}
+	if keyCheckFor(master) != desc.KeyCheck {
+		return fmt.Errorf("密码错误")
+	}
+	return a.unlockRule(rule, master, desc.EncryptNames, remember)
+}
+
+// LockRuleEncryption 锁定加密规则，清除内存和本机保存的密钥
+func (a *App) LockRuleEncryption(ruleID string) error {
+	a.cipherMu.Lock()
+	delete(a.ruleCiphers, ruleID)
+	a.cipherMu.Unlock()
+
+	return a.deleteSecret(ruleKeySecret(ruleID))
+}
+
+// IsRuleEncryptionUnlocked 检查加密规则是否已解锁，未加密的规则总是返回 true
+func (a *App) IsRuleEncryptionUnlocked(ruleID string) bool {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return false
+	}
+	_, err = a.ruleCipherFor(rule)
+	return err == nil
+}
+
+// unlockRule 缓存规则密钥，需要时保存到本机
+func (a *App) unlockRule(rule SyncRule, master []byte, encryptNames, remember bool) error {
+	if remember {
+		data, err := a.jsonParser.Marshal(ruleKeyFile{Key: hex.EncodeToString(master), KeyCheck: keyCheckFor(master), EncryptNames: encryptNames})
+		if err != nil {
+			return fmt.Errorf("序列化密钥失败: %v", err)
+		}
+		if err := a.setSecret(ruleKeySecret(rule.ID), string(data)); err != nil {
+			return err
+		}
+	}
+
+	a.cipherMu.Lock()
+	a.ruleCiphers[rule.ID] = newRuleCipher(master, remoteWatchPrefix(rule.RemotePath), encryptNames)
+	a.cipherMu.Unlock()
+	return nil
+}
+
+// ruleKeySecret 规则密钥在密钥存储中的名称
+func ruleKeySecret(ruleID string) string {
+	return "rule-key/" + ruleID
+}
+
+// encryptionDescriptorKey 规则密钥描述对象的键
+func (a *App) encryptionDescriptorKey(rule SyncRule) string {
+	return remoteWatchPrefix(rule.RemotePath) + encryptionDescriptorName
+}
+
+// loadEncryptionDescriptor 读取远程的密钥描述，不存在时返回nil
+func (a *App) loadEncryptionDescriptor(ctx context.Context, rule SyncRule) (*encryptionDescriptor, error) {
+	obj, _, err := a.getObject(ctx, a.encryptionDescriptorKey(rule), "")
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取密钥描述失败: %v", err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥描述失败: %v", err)
+	}
+
+	var desc encryptionDescriptor
+	if err := a.jsonParser.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("解析密钥描述失败: %v", err)
+	}
+	return &desc, nil
+}
+
+// saveEncryptionDescriptor 上传密钥描述
+func (a *App) saveEncryptionDescriptor(ctx context.Context, rule SyncRule, desc *encryptionDescriptor) error {
+	data, err := a.jsonParser.Marshal(desc)
+	if err != nil {
+		return fmt.Errorf("序列化密钥描述失败: %v", err)
+	}
+	sse, err := a.serverSideFor(a.encryptionDescriptorKey(rule))
+	if err != nil {
+		return err
+	}
+	_, err = a.minioClient.PutObject(ctx, a.minioConfig.BucketName, a.encryptionDescriptorKey(rule),
+		bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: "application/json", ServerSideEncryption: sse})
+	if err != nil {
+		return fmt.Errorf("上传密钥描述失败: %v", err)
+	}
+	return nil
+}
+
+// ruleCipherFor 获取规则的加密器：未加密返回nil；已加密但未解锁时返回错误
+func (a *App) ruleCipherFor(rule SyncRule) (*ruleCipher, error) {
+	if !rule.Encryption.Enabled {
+		return nil, nil
+	}
+
+	a.cipherMu.Lock()
+	defer a.cipherMu.Unlock()
+	if enc, exists := a.ruleCiphers[rule.ID]; exists {
+		return enc, nil
+	}
+
+	// 使用本机保存的密钥
+	data, err := a.getSecret(ruleKeySecret(rule.ID))
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return nil, fmt.Errorf("规则 '%s' 已加密，请先输入密码解锁", rule.Name)
+	}
+	var saved ruleKeyFile
+	if err := a.jsonParser.Unmarshal([]byte(data), &saved); err != nil {
+		return nil, fmt.Errorf("解析保存的密钥失败: %v", err)
+	}
+	master, err := hex.DecodeString(saved.Key)
+	if err != nil || keyCheckFor(master) != saved.KeyCheck {
+		return nil, fmt.Errorf("保存的密钥已损坏，请重新解锁规则 '%s'", rule.Name)
+	}
+
+	enc := newRuleCipher(master, remoteWatchPrefix(rule.RemotePath), saved.EncryptNames)
+	a.ruleCiphers[rule.ID] = enc
+	return enc, nil
+}
+
+// cipherForKey 获取对象所属加密规则的加密器，对象不属于任何加密规则时返回nil
+func (a *App) cipherForKey(key string) (*ruleCipher, error) {
+	rule, ok := a.ruleForKey(key, func(rule SyncRule) bool { return rule.Encryption.Enabled })
+	if !ok {
+		return nil, nil
+	}
+	return a.ruleCipherFor(rule)
+}
+
+// storedChecksum 对象元数据中应有的校验和：加密规则下为带密钥的校验和
+func (a *App) storedChecksum(remotePath, checksum string) (string, error) {
+	enc, err := a.cipherForKey(remotePath)
+	if err != nil || enc == nil {
+		return checksum, err
+	}
+	return enc.checksum(checksum), nil
+}
+
+// putEncrypted 加密上传内容，size 为明文大小
+// 加密内容每次都不同，不使用断点续传
+func (a *App) putEncrypted(ctx context.Context, enc *ruleCipher, remotePath string, r io.Reader, size int64, metadata map[string]string, checksum string, progress io.Reader) (minio.UploadInfo, error) {
+	encrypted, err := enc.encryptReader(r)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	sse, err := a.serverSideFor(remotePath)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	info, err := a.minioClient.PutObject(ctx, a.minioConfig.BucketName, remotePath, a.limitUpload(ctx, encrypted), enc.encryptedSize(size), minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		UserMetadata:         enc.objectMetadata(metadata, checksum, size),
+		Progress:             progress,
+		ServerSideEncryption: sse,
+	})
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("上传文件失败: %v", err)
+	}
+	return info, nil
+}
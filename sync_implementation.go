@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,21 +11,45 @@ import (
 
 // SyncConfig 存储同步配置
 type SyncConfig struct {
-	LocalPath  string `json:"localPath"`
-	RemotePath string `json:"remotePath"`
-	Direction  string `json:"direction"` // "up", "down", "both"
-	Interval   int    `json:"interval"`  // 同步间隔（秒）
+	RuleID           string `json:"ruleId"`
+	LocalPath        string `json:"localPath"`
+	RemotePath       string `json:"remotePath"`
+	Direction        string `json:"direction"`        // "up", "down", "both"
+	Interval         int    `json:"interval"`         // 同步间隔（秒）
+	MaxDeletePercent int    `json:"maxDeletePercent"` // 单次同步允许删除的文件比例上限
+	Concurrency      int    `json:"concurrency"`      // 并发传输数，0 表示使用全局设置
 }
 
 // SyncRule 同步规则
 type SyncRule struct {
-	ID         string   `json:"id"`
-	Name       string   `json:"name"`
-	LocalPath  string   `json:"localPath"`
-	RemotePath string   `json:"remotePath"`
-	Direction  string   `json:"direction"`
-	Filters    []string `json:"filters"`
-	Enabled    bool     `json:"enabled"`
+	ID               string           `json:"id"`
+	Name             string           `json:"name"`
+	LocalPath        string           `json:"localPath"`
+	RemotePath       string           `json:"remotePath"`
+	Direction        string           `json:"direction"`
+	Filters          []string         `json:"filters"`
+	Enabled          bool             `json:"enabled"`
+	MaxDeletePercent int              `json:"maxDeletePercent"` // 双向同步删除保护阈值（百分比），0 表示使用默认值
+	Concurrency      int              `json:"concurrency"`      // 并发传输数，0 表示使用全局设置
+	Retention        BackupRetention  `json:"retention"`        // 备份模式的保留策略
+	Encryption       RuleEncryption   `json:"encryption"`       // 客户端加密
+	ServerEncryption ServerEncryption `json:"serverEncryption"` // 服务端加密，未设置时使用全局设置
+	Compression      RuleCompression  `json:"compression"`      // 上传压缩
+	Chunking         RuleChunking     `json:"chunking"`         // 大文件分块存储
+	PreserveOwner    bool             `json:"preserveOwner"`    // 记录并还原文件所有者（uid:gid），默认关闭
+}
+
+// syncConfigFromRule 根据同步规则创建同步配置
+func syncConfigFromRule(rule SyncRule) SyncConfig {
+	return SyncConfig{
+		RuleID:           rule.ID,
+		LocalPath:        rule.LocalPath,
+		RemotePath:       rule.RemotePath,
+		Direction:        rule.Direction,
+		Interval:         60, // 默认60秒
+		MaxDeletePercent: rule.MaxDeletePercent,
+		Concurrency:      rule.Concurrency,
+	}
 }
 
 // SyncStatus 同步状态
@@ -53,10 +78,13 @@ type MinioFileInfo struct {
 	Size         int64     `json:"size"`
 	LastModified time.Time `json:"lastModified"`
 	IsDir        bool      `json:"isDir"`
+	ETag         string    `json:"etag"`
+	VersionID    string    `json:"versionId"`
+	SHA256       string    `json:"sha256"` // 上传时写入元数据的内容校验和，列表结果中为空
 }
 
 // syncUp 将本地文件同步到远程
-func (a *App) syncUp(config SyncConfig) error {
+func (a *App) syncUp(ctx context.Context, config SyncConfig) error {
 	fmt.Printf("开始上传同步: %s -> %s\n", config.LocalPath, config.RemotePath)
 
 	// 检查本地路径是否存在
@@ -93,55 +121,37 @@ func (a *App) syncUp(config SyncConfig) error {
 		}
 	}
 
-	// 上传新文件或更新的文件
-	uploadCount := 0
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	// 确定需要上传的新文件或更新的文件
+	var tasks []transferTask
 	for _, localFile := range localFiles {
-		// 计算相对路径
-		relPath, err := filepath.Rel(config.LocalPath, localFile)
+		task, err := a.planUpload(index, config, localFile, remoteFileMap)
 		if err != nil {
-			return fmt.Errorf("计算相对路径失败: %v", err)
+			return err
 		}
-
-		// 转换为远程路径
-		remotePath := filepath.Join(config.RemotePath, relPath)
-		remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-
-		// 获取本地文件的修改时间
-		localModTime, err := getFileModTime(localFile)
-		if err != nil {
-			return fmt.Errorf("获取本地文件修改时间失败: %v", err)
+		if task != nil {
+			tasks = append(tasks, *task)
 		}
+	}
 
-		// 检查远程文件是否存在
-		remoteFile, exists := remoteFileMap[remotePath]
-		if !exists {
-			// 文件不存在，上传
-			fmt.Printf("上传新文件: %s -> %s\n", localFile, remotePath)
-			err = a.UploadFileToMinio(localFile, remotePath)
-			if err != nil {
-				return fmt.Errorf("上传文件失败: %v", err)
-			}
-			uploadCount++
-		} else {
-			// 文件存在，检查修改时间
-			if localModTime.After(remoteFile.LastModified) {
-				// 本地文件更新，上传
-				fmt.Printf("上传更新的文件: %s -> %s\n", localFile, remotePath)
-				err = a.UploadFileToMinio(localFile, remotePath)
-				if err != nil {
-					return fmt.Errorf("上传文件失败: %v", err)
-				}
-				uploadCount++
-			}
-		}
+	// 并发上传
+	result := a.runTransfers(ctx, config, index, tasks)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	fmt.Printf("上传同步完成，共上传 %d 个文件\n", uploadCount)
-	return nil
+	fmt.Printf("上传同步完成，共上传 %d 个文件\n", result.Uploaded)
+	return result.err()
 }
 
 // syncDown 将远程文件同步到本地
-func (a *App) syncDown(config SyncConfig) error {
+func (a *App) syncDown(ctx context.Context, config SyncConfig) error {
 	fmt.Printf("开始下载同步: %s -> %s\n", config.RemotePath, config.LocalPath)
 
 	// 确保本地路径存在
@@ -155,99 +165,42 @@ func (a *App) syncDown(config SyncConfig) error {
 		return fmt.Errorf("获取远程文件列表失败: %v", err)
 	}
 
-	// 获取本地文件列表
-	localFiles, err := getAllFiles(config.LocalPath)
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
 	if err != nil {
-		return fmt.Errorf("获取本地文件列表失败: %v", err)
-	}
-
-	// 创建本地文件映射，用于快速查找
-	localFileMap := make(map[string]string)
-	for _, file := range localFiles {
-		// 计算相对路径
-		relPath, err := filepath.Rel(config.LocalPath, file)
-		if err != nil {
-			return fmt.Errorf("计算相对路径失败: %v", err)
-		}
-		localFileMap[relPath] = file
+		return err
 	}
+	defer a.saveSyncIndexQuietly(index)
 
-	// 下载新文件或更新的文件
-	downloadCount := 0
+	// 确定需要下载的新文件或更新的文件
+	var tasks []transferTask
 	for _, remoteFile := range remoteFiles {
 		// 跳过目录
 		if remoteFile.IsDir {
 			continue
 		}
 
-		// 计算相对路径
-		relPath := strings.TrimPrefix(remoteFile.Path, config.RemotePath)
-		if strings.HasPrefix(relPath, "/") {
-			relPath = relPath[1:]
+		task, err := a.planDownload(index, config, remoteFile)
+		if err != nil {
+			return err
 		}
-
-		// 转换为本地路径
-		localPath := filepath.Join(config.LocalPath, relPath)
-
-		// 检查本地文件是否存在
-		existingLocalPath, exists := localFileMap[relPath]
-		if !exists {
-			// 文件不存在，下载
-			fmt.Printf("下载新文件: %s -> %s\n", remoteFile.Path, localPath)
-
-			// 确保本地目录存在
-			localDir := filepath.Dir(localPath)
-			if err := os.MkdirAll(localDir, 0755); err != nil {
-				return fmt.Errorf("创建本地目录失败: %v", err)
-			}
-
-			// 下载文件
-			data, err := a.DownloadFileFromMinio(remoteFile.Path)
-			if err != nil {
-				return fmt.Errorf("下载文件失败: %v", err)
-			}
-
-			// 写入本地文件
-			err = os.WriteFile(localPath, data, 0644)
-			if err != nil {
-				return fmt.Errorf("写入本地文件失败: %v", err)
-			}
-
-			downloadCount++
-		} else {
-			// 文件存在，检查修改时间
-			localModTime, err := getFileModTime(existingLocalPath)
-			if err != nil {
-				return fmt.Errorf("获取本地文件修改时间失败: %v", err)
-			}
-
-			if remoteFile.LastModified.After(localModTime) {
-				// 远程文件更新，下载
-				fmt.Printf("下载更新的文件: %s -> %s\n", remoteFile.Path, localPath)
-
-				// 下载文件
-				data, err := a.DownloadFileFromMinio(remoteFile.Path)
-				if err != nil {
-					return fmt.Errorf("下载文件失败: %v", err)
-				}
-
-				// 写入本地文件
-				err = os.WriteFile(localPath, data, 0644)
-				if err != nil {
-					return fmt.Errorf("写入本地文件失败: %v", err)
-				}
-
-				downloadCount++
-			}
+		if task != nil {
+			tasks = append(tasks, *task)
 		}
 	}
 
-	fmt.Printf("下载同步完成，共下载 %d 个文件\n", downloadCount)
-	return nil
+	// 并发下载
+	result := a.runTransfers(ctx, config, index, tasks)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fmt.Printf("下载同步完成，共下载 %d 个文件\n", result.Downloaded)
+	return result.err()
 }
 
 // fullSync 执行完整同步
-func (a *App) fullSync(status *SyncStatus) error {
+func (a *App) fullSync(ctx context.Context, status *SyncStatus) error {
 	fmt.Println("执行完整同步...")
 
 	// 获取所有同步规则
@@ -258,61 +211,55 @@ func (a *App) fullSync(status *SyncStatus) error {
 
 	// 遍历所有规则
 	for _, rule := range rules {
+		// 暂停时等待恢复，取消时中止同步
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+
 		// 跳过禁用的规则
 		if !rule.Enabled {
 			continue
 		}
 
 		// 创建同步配置
-		config := SyncConfig{
-			LocalPath:  rule.LocalPath,
-			RemotePath: rule.RemotePath,
-			Direction:  rule.Direction,
-			Interval:   60, // 默认60秒
+		config := syncConfigFromRule(rule)
+
+		// 识别重命名和移动，在服务端或本地直接执行，避免重新传输
+		if err := a.applyRenames(ctx, config, status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("检测重命名失败: %v", err))
 		}
 
 		// 检测冲突
-		conflicts, err := a.detectConflicts(rule.LocalPath, rule.RemotePath)
-		if err != nil {
-			status.Errors = append(status.Errors, fmt.Sprintf("检测冲突失败: %v", err))
-		} else if len(conflicts) > 0 {
-			// 添加到冲突列表
-			a.conflictFiles = append(a.conflictFiles, conflicts...)
-			status.ConflictCount += len(conflicts)
-			
-			// 如果有冲突且默认解决方式不是询问，自动解决冲突
-			if a.defaultConflictResolution != "ask" {
-				for _, conflict := range conflicts {
-					err := a.ResolveConflict(conflict.Path, a.defaultConflictResolution)
-					if err != nil {
-						status.Errors = append(status.Errors, fmt.Sprintf("解决冲突失败: %v", err))
-					}
-				}
-			}
-		}
+		a.handleRuleConflicts(ctx, config, status)
 
 		// 根据方向执行同步
+		var err error
 		switch rule.Direction {
 		case "upload":
-			err = a.syncUp(config)
+			err = a.syncUp(ctx, config)
 			if err == nil {
 				status.FilesUploaded++
 			}
 		case "download":
-			err = a.syncDown(config)
+			err = a.syncDown(ctx, config)
 			if err == nil {
 				status.FilesDownloaded++
 			}
 		case "bidirectional":
+			// 先传播两端的删除，触发删除保护时跳过本规则
+			if err = a.propagateDeletions(ctx, config, status); err != nil {
+				break
+			}
+
 			// 先上传再下载
-			err = a.syncUp(config)
+			err = a.syncUp(ctx, config)
 			if err == nil {
 				status.FilesUploaded++
 			} else {
 				status.Errors = append(status.Errors, fmt.Sprintf("上传同步失败: %v", err))
 			}
 
-			err = a.syncDown(config)
+			err = a.syncDown(ctx, config)
 			if err == nil {
 				status.FilesDownloaded++
 			}
@@ -332,7 +279,7 @@ func (a *App) fullSync(status *SyncStatus) error {
 }
 
 // selectiveSync 执行选择性同步
-func (a *App) selectiveSync(status *SyncStatus) error {
+func (a *App) selectiveSync(ctx context.Context, status *SyncStatus) error {
 	fmt.Println("执行选择性同步...")
 
 	// 获取所有同步规则
@@ -343,17 +290,22 @@ func (a *App) selectiveSync(status *SyncStatus) error {
 
 	// 遍历所有规则
 	for _, rule := range rules {
+		// 暂停时等待恢复，取消时中止同步
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+
 		// 跳过禁用的规则
 		if !rule.Enabled {
 			continue
 		}
 
 		// 创建同步配置
-		config := SyncConfig{
-			LocalPath:  rule.LocalPath,
-			RemotePath: rule.RemotePath,
-			Direction:  rule.Direction,
-			Interval:   60, // 默认60秒
+		config := syncConfigFromRule(rule)
+
+		// 识别重命名和移动，在服务端或本地直接执行，避免重新传输
+		if err := a.applyRenames(ctx, config, status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("检测重命名失败: %v", err))
 		}
 
 		// 获取本地文件列表
@@ -376,30 +328,11 @@ func (a *App) selectiveSync(status *SyncStatus) error {
 		switch rule.Direction {
 		case "upload":
 			// 只上传过滤后的文件
-			for _, file := range filteredFiles {
-				// 计算相对路径
-				relPath, err := filepath.Rel(config.LocalPath, file)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("计算相对路径失败: %v", err))
-					continue
-				}
-
-				// 转换为远程路径
-				remotePath := filepath.Join(config.RemotePath, relPath)
-				remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-
-				// 上传文件
-				err = a.UploadFileToMinio(file, remotePath)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
-				} else {
-					status.FilesUploaded++
-				}
-			}
+			a.syncUpFiles(ctx, config, filteredFiles, status)
 
 		case "download":
 			// 执行下载同步
-			err = a.syncDown(config)
+			err = a.syncDown(ctx, config)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("下载同步失败: %v", err))
 			} else {
@@ -407,30 +340,20 @@ func (a *App) selectiveSync(status *SyncStatus) error {
 			}
 
 		case "bidirectional":
-			// 只上传过滤后的文件
-			for _, file := range filteredFiles {
-				// 计算相对路径
-				relPath, err := filepath.Rel(config.LocalPath, file)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("计算相对路径失败: %v", err))
-					continue
-				}
-
-				// 转换为远程路径
-				remotePath := filepath.Join(config.RemotePath, relPath)
-				remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-
-				// 上传文件
-				err = a.UploadFileToMinio(file, remotePath)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
-				} else {
-					status.FilesUploaded++
-				}
+			// 先传播两端的删除，触发删除保护时跳过本规则
+			if err := a.propagateDeletions(ctx, config, status); err != nil {
+				status.Errors = append(status.Errors, fmt.Sprintf("同步规则 '%s' 失败: %v", rule.Name, err))
+				continue
 			}
 
+			// 检测冲突
+			a.handleRuleConflicts(ctx, config, status)
+
+			// 只上传过滤后的文件
+			a.syncUpFiles(ctx, config, filteredFiles, status)
+
 			// 执行下载同步
-			err = a.syncDown(config)
+			err = a.syncDown(ctx, config)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("下载同步失败: %v", err))
 			} else {
@@ -445,8 +368,48 @@ func (a *App) selectiveSync(status *SyncStatus) error {
 	return nil
 }
 
+// syncUpFiles 按三方比较结果上传指定的本地文件，错误记录到同步状态中
+func (a *App) syncUpFiles(ctx context.Context, config SyncConfig, files []string, status *SyncStatus) {
+	// 获取远程文件列表
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
+	if err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("获取远程文件列表失败: %v", err))
+		return
+	}
+
+	// 创建远程文件映射，用于快速查找
+	remoteFileMap := make(map[string]MinioFileInfo)
+	for _, file := range remoteFiles {
+		if !file.IsDir {
+			remoteFileMap[file.Path] = file
+		}
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		status.Errors = append(status.Errors, err.Error())
+		return
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	var tasks []transferTask
+	for _, file := range files {
+		task, err := a.planUpload(index, config, file, remoteFileMap)
+		if err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
+		} else if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+
+	result := a.runTransfers(ctx, config, index, tasks)
+	status.FilesUploaded += result.Uploaded
+	status.Errors = append(status.Errors, result.Errors...)
+}
+
 // backupSync 执行备份同步
-func (a *App) backupSync(status *SyncStatus) error {
+func (a *App) backupSync(ctx context.Context, status *SyncStatus) error {
 	fmt.Println("执行备份同步...")
 
 	// 获取所有同步规则
@@ -457,54 +420,28 @@ func (a *App) backupSync(status *SyncStatus) error {
 
 	// 遍历所有规则
 	for _, rule := range rules {
-		// 跳过禁用的规则
-		if !rule.Enabled {
-			continue
-		}
-
-		// 创建同步配置
-		config := SyncConfig{
-			LocalPath:  rule.LocalPath,
-			RemotePath: rule.RemotePath,
-			Direction:  rule.Direction,
-			Interval:   60, // 默认60秒
+		// 暂停时等待恢复，取消时中止同步
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
 		}
 
-		// 创建备份文件夹
-		backupPath := filepath.Join(config.RemotePath, fmt.Sprintf("backup_%s", time.Now().Format("20060102_150405")))
-		err := a.CreateMinioFolder(backupPath)
-		if err != nil {
-			status.Errors = append(status.Errors, fmt.Sprintf("创建备份文件夹失败: %v", err))
+		// 跳过禁用的规则
+		if !rule.Enabled {
 			continue
 		}
 
-		// 获取本地文件列表
-		localFiles, err := getAllFiles(config.LocalPath)
-		if err != nil {
-			status.Errors = append(status.Errors, fmt.Sprintf("获取本地文件列表失败: %v", err))
+		// 创建去重快照，只上传新的内容
+		if err := a.runBackup(ctx, rule, status); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			status.Errors = append(status.Errors, fmt.Sprintf("规则 '%s' 备份失败: %v", rule.Name, err))
 			continue
 		}
 
-		// 上传文件到备份文件夹
-		for _, file := range localFiles {
-			// 计算相对路径
-			relPath, err := filepath.Rel(config.LocalPath, file)
-			if err != nil {
-				status.Errors = append(status.Errors, fmt.Sprintf("计算相对路径失败: %v", err))
-				continue
-			}
-
-			// 转换为远程路径
-			remotePath := filepath.Join(backupPath, relPath)
-			remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-
-			// 上传文件
-			err = a.UploadFileToMinio(file, remotePath)
-			if err != nil {
-				status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
-			} else {
-				status.FilesUploaded++
-			}
+		// 本次备份完整时才清理旧备份，避免只剩下不完整的备份
+		if _, err := a.pruneBackupSnapshots(ctx, rule); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("清理旧备份失败: %v", err))
 		}
 	}
 
@@ -532,7 +469,8 @@ func getAllFiles(root string) ([]string, error) {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
+		// 跳过下载过程中产生的临时文件
+		if !info.IsDir() && !isTempDownloadFile(path) {
 			files = append(files, path)
 		}
 		return nil
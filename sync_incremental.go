@@ -1,15 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
 )
 
 // incrementalSync 执行增量同步
-func (a *App) incrementalSync(status *SyncStatus) error {
+func (a *App) incrementalSync(ctx context.Context, status *SyncStatus) error {
 	fmt.Println("执行增量同步...")
 
 	// 获取所有同步规则
@@ -18,44 +17,55 @@ func (a *App) incrementalSync(status *SyncStatus) error {
 		return fmt.Errorf("没有同步规则")
 	}
 
-	// 获取上次同步时间
-	lastSyncTime := a.lastSyncTime
-
 	// 遍历所有规则
 	for _, rule := range rules {
+		// 暂停时等待恢复，取消时中止同步
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+
 		// 跳过禁用的规则
 		if !rule.Enabled {
 			continue
 		}
 
 		// 创建同步配置
-		config := SyncConfig{
-			LocalPath:  rule.LocalPath,
-			RemotePath: rule.RemotePath,
-			Direction:  rule.Direction,
-			Interval:   60, // 默认60秒
+		config := syncConfigFromRule(rule)
+
+		// 识别重命名和移动，在服务端或本地直接执行，避免重新传输
+		if err := a.applyRenames(ctx, config, status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("检测重命名失败: %v", err))
 		}
 
+		// 检测冲突
+		a.handleRuleConflicts(ctx, config, status)
+
 		// 根据方向执行同步
 		switch rule.Direction {
 		case "upload":
-			err := a.incrementalSyncUp(config, lastSyncTime, status, rule.Filters)
+			err := a.incrementalSyncUp(ctx, config, status, rule.Filters)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("上传同步失败: %v", err))
 			}
 		case "download":
-			err := a.incrementalSyncDown(config, lastSyncTime, status, rule.Filters)
+			err := a.incrementalSyncDown(ctx, config, status, rule.Filters)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("下载同步失败: %v", err))
 			}
 		case "bidirectional":
+			// 先传播两端的删除，触发删除保护时跳过本规则
+			if err := a.propagateDeletions(ctx, config, status); err != nil {
+				status.Errors = append(status.Errors, fmt.Sprintf("同步规则 '%s' 失败: %v", rule.Name, err))
+				continue
+			}
+
 			// 先上传再下载
-			err := a.incrementalSyncUp(config, lastSyncTime, status, rule.Filters)
+			err := a.incrementalSyncUp(ctx, config, status, rule.Filters)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("上传同步失败: %v", err))
 			}
 
-			err = a.incrementalSyncDown(config, lastSyncTime, status, rule.Filters)
+			err = a.incrementalSyncDown(ctx, config, status, rule.Filters)
 			if err != nil {
 				status.Errors = append(status.Errors, fmt.Sprintf("下载同步失败: %v", err))
 			}
@@ -68,7 +78,8 @@ func (a *App) incrementalSync(status *SyncStatus) error {
 }
 
 // incrementalSyncUp 执行增量上传同步
-func (a *App) incrementalSyncUp(config SyncConfig, lastSyncTime time.Time, status *SyncStatus, filters []string) error {
+// 通过同步索引判断文件是否变化，大小和修改时间未变的文件无需计算哈希
+func (a *App) incrementalSyncUp(ctx context.Context, config SyncConfig, status *SyncStatus, filters []string) error {
 	fmt.Printf("开始增量上传同步: %s -> %s\n", config.LocalPath, config.RemotePath)
 
 	// 检查本地路径是否存在
@@ -105,56 +116,43 @@ func (a *App) incrementalSyncUp(config SyncConfig, lastSyncTime time.Time, statu
 		}
 	}
 
-	// 上传新文件或更新的文件
-	uploadCount := 0
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	// 确定需要上传的新文件或更新的文件
+	var tasks []transferTask
 	for _, localFile := range localFiles {
 		// 检查是否匹配过滤规则
 		if matchesFilter(localFile, filters) {
 			continue
 		}
 
-		// 获取文件修改时间
-		fileInfo, err := os.Stat(localFile)
+		task, err := a.planUpload(index, config, localFile, remoteFileMap)
 		if err != nil {
-			status.Errors = append(status.Errors, fmt.Sprintf("获取文件信息失败: %v", err))
+			status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
 			continue
 		}
-
-		// 如果文件在上次同步后被修改，则上传
-		if fileInfo.ModTime().After(lastSyncTime) {
-			// 计算相对路径
-			relPath, err := filepath.Rel(config.LocalPath, localFile)
-			if err != nil {
-				status.Errors = append(status.Errors, fmt.Sprintf("计算相对路径失败: %v", err))
-				continue
-			}
-
-			// 转换为远程路径
-			remotePath := filepath.Join(config.RemotePath, relPath)
-			remotePath = strings.ReplaceAll(remotePath, "\\", "/")
-
-			// 检查远程文件是否存在
-			remoteFile, exists := remoteFileMap[remotePath]
-			if !exists || fileInfo.ModTime().After(remoteFile.LastModified) {
-				// 文件不存在或本地文件更新，上传
-				fmt.Printf("上传文件: %s -> %s\n", localFile, remotePath)
-				err = a.UploadFileToMinio(localFile, remotePath)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
-				} else {
-					uploadCount++
-					status.FilesUploaded++
-				}
-			}
+		if task != nil {
+			tasks = append(tasks, *task)
 		}
 	}
 
-	fmt.Printf("增量上传同步完成，共上传 %d 个文件\n", uploadCount)
+	// 并发上传
+	result := a.runTransfers(ctx, config, index, tasks)
+	status.FilesUploaded += result.Uploaded
+	status.Errors = append(status.Errors, result.Errors...)
+
+	fmt.Printf("增量上传同步完成，共上传 %d 个文件\n", result.Uploaded)
 	return nil
 }
 
 // incrementalSyncDown 执行增量下载同步
-func (a *App) incrementalSyncDown(config SyncConfig, lastSyncTime time.Time, status *SyncStatus, filters []string) error {
+// 通过对象ETag与同步索引比较判断远程是否变化
+func (a *App) incrementalSyncDown(ctx context.Context, config SyncConfig, status *SyncStatus, filters []string) error {
 	fmt.Printf("开始增量下载同步: %s -> %s\n", config.RemotePath, config.LocalPath)
 
 	// 确保本地路径存在
@@ -168,111 +166,41 @@ func (a *App) incrementalSyncDown(config SyncConfig, lastSyncTime time.Time, sta
 		return fmt.Errorf("获取远程文件列表失败: %v", err)
 	}
 
-	// 获取本地文件列表
-	localFiles, err := getAllFiles(config.LocalPath)
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
 	if err != nil {
-		return fmt.Errorf("获取本地文件列表失败: %v", err)
+		return err
 	}
+	defer a.saveSyncIndexQuietly(index)
 
-	// 创建本地文件映射，用于快速查找
-	localFileMap := make(map[string]string)
-	for _, file := range localFiles {
-		// 计算相对路径
-		relPath, err := filepath.Rel(config.LocalPath, file)
-		if err != nil {
-			return fmt.Errorf("计算相对路径失败: %v", err)
-		}
-		localFileMap[relPath] = file
-	}
-
-	// 下载新文件或更新的文件
-	downloadCount := 0
+	// 确定需要下载的新文件或更新的文件
+	var tasks []transferTask
 	for _, remoteFile := range remoteFiles {
 		// 跳过目录
 		if remoteFile.IsDir {
 			continue
 		}
 
-		// 如果文件在上次同步后被修改，则下载
-		if remoteFile.LastModified.After(lastSyncTime) {
-			// 计算相对路径
-			relPath := strings.TrimPrefix(remoteFile.Path, config.RemotePath)
-			if strings.HasPrefix(relPath, "/") {
-				relPath = relPath[1:]
-			}
-
-			// 检查是否匹配过滤规则
-			if matchesFilter(relPath, filters) {
-				continue
-			}
-
-			// 转换为本地路径
-			localPath := filepath.Join(config.LocalPath, relPath)
-
-			// 检查本地文件是否存在
-			existingLocalPath, exists := localFileMap[relPath]
-			if !exists {
-				// 文件不存在，下载
-				fmt.Printf("下载新文件: %s -> %s\n", remoteFile.Path, localPath)
-
-				// 确保本地目录存在
-				localDir := filepath.Dir(localPath)
-				if err := os.MkdirAll(localDir, 0755); err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("创建本地目录失败: %v", err))
-					continue
-				}
-
-				// 下载文件
-				data, err := a.DownloadFileFromMinio(remoteFile.Path)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("下载文件失败: %v", err))
-					continue
-				}
-
-				// 写入本地文件
-				err = os.WriteFile(localPath, data, 0644)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("写入本地文件失败: %v", err))
-					continue
-				}
-
-				downloadCount++
-				status.FilesDownloaded++
-			} else {
-				// 文件存在，检查修改时间
-				localModTime, err := getFileModTime(existingLocalPath)
-				if err != nil {
-					status.Errors = append(status.Errors, fmt.Sprintf("获取本地文件修改时间失败: %v", err))
-					continue
-				}
-
-				if remoteFile.LastModified.After(localModTime) {
-					// 远程文件更新，下载
-					fmt.Printf("下载更新的文件: %s -> %s\n", remoteFile.Path, localPath)
-
-					// 下载文件
-					data, err := a.DownloadFileFromMinio(remoteFile.Path)
-					if err != nil {
-						status.Errors = append(status.Errors, fmt.Sprintf("下载文件失败: %v", err))
-						continue
-					}
-
-					// 写入本地文件
-					err = os.WriteFile(localPath, data, 0644)
-					if err != nil {
-						status.Errors = append(status.Errors, fmt.Sprintf("写入本地文件失败: %v", err))
-						continue
-					}
+		// 检查是否匹配过滤规则
+		if matchesFilter(a.syncRemoteRelPath(config.RemotePath, remoteFile.Path), filters) {
+			continue
+		}
 
-					downloadCount++
-					status.FilesDownloaded++
-				}
-			}
+		task, err := a.planDownload(index, config, remoteFile)
+		if err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("下载文件失败: %v", err))
+			continue
+		}
+		if task != nil {
+			tasks = append(tasks, *task)
 		}
 	}
 
-	fmt.Printf("增量下载同步完成，共下载 %d 个文件\n", downloadCount)
+	// 并发下载
+	result := a.runTransfers(ctx, config, index, tasks)
+	status.FilesDownloaded += result.Downloaded
+	status.Errors = append(status.Errors, result.Errors...)
+
+	fmt.Printf("增量下载同步完成，共下载 %d 个文件\n", result.Downloaded)
 	return nil
 }
-
-
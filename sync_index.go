@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 三方比较得出的文件变化类型
+const (
+	SyncChangeNone   = "none"   // 本地和远程都未变化
+	SyncChangeLocal  = "local"  // 仅本地有变化
+	SyncChangeRemote = "remote" // 仅远程有变化
+	SyncChangeBoth   = "both"   // 本地和远程都有变化
+)
+
+// SyncIndexEntry 同步索引条目，记录文件上一次同步成功时的状态
+type SyncIndexEntry struct {
+	Path         string    `json:"path"` // 相对路径，统一使用 / 分隔
+	Size         int64     `json:"size"`
+	LocalModTime time.Time `json:"localModTime"`
+	LocalHash    string    `json:"localHash"` // 本地文件MD5
+	RemoteETag   string    `json:"remoteETag"`
+	VersionID    string    `json:"versionId"`
+	SyncedAt     time.Time `json:"syncedAt"`
+}
+
+// SyncIndex 单条同步规则的持久化同步索引
+type SyncIndex struct {
+	RuleID    string                    `json:"ruleId"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+	Entries   map[string]SyncIndexEntry `json:"entries"`
+
+	mu sync.Mutex // 并发传输时保护 Entries
+}
+
+// syncIndexKey 获取同步配置对应的索引标识
+func syncIndexKey(config SyncConfig) string {
+	if config.RuleID != "" {
+		return config.RuleID
+	}
+
+	// 没有规则ID时，根据本地和远程路径生成稳定的标识
+	sum := md5.Sum([]byte(config.LocalPath + "|" + config.RemotePath))
+	return "path_" + hex.EncodeToString(sum[:])
+}
+
+// syncIndexPath 获取同步索引文件路径
+func (a *App) syncIndexPath(key string) string {
+	return filepath.Join(a.configDir, "sync_index", key+".json")
+}
+
+// loadSyncIndex 加载同步索引，不存在时返回空索引
+func (a *App) loadSyncIndex(config SyncConfig) (*SyncIndex, error) {
+	// 加密规则未解锁时无法对应远程文件名和校验和，不能同步
+	if _, err := a.cipherForKey(remoteWatchPrefix(config.RemotePath)); err != nil {
+		return nil, err
+	}
+
+	key := syncIndexKey(config)
+	index := &SyncIndex{
+		RuleID:  key,
+		Entries: make(map[string]SyncIndexEntry),
+	}
+
+	// 检查文件是否存在
+	indexPath := a.syncIndexPath(key)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return index, nil
+	}
+
+	// 读取文件
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取同步索引失败: %v", err)
+	}
+
+	// 解析JSON
+	if err := a.jsonParser.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("解析同步索引失败: %v", err)
+	}
+	if index.Entries == nil {
+		index.Entries = make(map[string]SyncIndexEntry)
+	}
+
+	return index, nil
+}
+
+// saveSyncIndex 保存同步索引
+func (a *App) saveSyncIndex(index *SyncIndex) error {
+	indexPath := a.syncIndexPath(index.RuleID)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("创建同步索引目录失败: %v", err)
+	}
+
+	index.UpdatedAt = time.Now()
+
+	// 序列化为JSON
+	data, err := a.jsonParser.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("序列化同步索引失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致索引损坏
+	tmpPath := indexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入同步索引失败: %v", err)
+	}
+
+	return os.Rename(tmpPath, indexPath)
+}
+
+// removeSyncIndex 删除同步规则对应的同步索引
+func (a *App) removeSyncIndex(ruleID string) error {
+	err := os.Remove(a.syncIndexPath(ruleID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除同步索引失败: %v", err)
+	}
+	return nil
+}
+
+// localChanged 判断本地文件相对于索引记录是否发生变化
+// 大小和修改时间都未变时直接认为未变化；否则计算哈希确认，避免仅被 touch 的文件被误判
+func (idx *SyncIndex) localChanged(relPath, localPath string, info os.FileInfo) (bool, string, error) {
+	idx.mu.Lock()
+	entry, exists := idx.Entries[relPath]
+	idx.mu.Unlock()
+	if !exists {
+		return true, "", nil
+	}
+
+	if info.Size() == entry.Size && info.ModTime().Equal(entry.LocalModTime) {
+		return false, entry.LocalHash, nil
+	}
+
+	hash, err := calculateMD5(localPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	if hash == entry.LocalHash {
+		// 内容未变化，仅更新修改时间，下次无需再计算哈希
+		entry.Size = info.Size()
+		entry.LocalModTime = info.ModTime()
+		idx.mu.Lock()
+		idx.Entries[relPath] = entry
+		idx.mu.Unlock()
+		return false, hash, nil
+	}
+
+	return true, hash, nil
+}
+
+// remoteChanged 判断远程对象相对于索引记录是否发生变化
+func (idx *SyncIndex) remoteChanged(relPath string, remote MinioFileInfo) bool {
+	idx.mu.Lock()
+	entry, exists := idx.Entries[relPath]
+	idx.mu.Unlock()
+	if !exists {
+		return true
+	}
+	return remote.ETag != entry.RemoteETag
+}
+
+// classify 对本地文件和远程对象做三方比较，返回变化类型
+// local 为 nil 表示本地文件不存在，remote 为 nil 表示远程对象不存在
+func (idx *SyncIndex) classify(relPath, localPath string, local os.FileInfo, remote *MinioFileInfo) (string, error) {
+	idx.mu.Lock()
+	_, known := idx.Entries[relPath]
+	idx.mu.Unlock()
+
+	// 索引中没有记录：首次同步该文件
+	if !known {
+		switch {
+		case local != nil && remote == nil:
+			return SyncChangeLocal, nil
+		case local == nil && remote != nil:
+			return SyncChangeRemote, nil
+		case local == nil && remote == nil:
+			return SyncChangeNone, nil
+		}
+
+		// 两端都存在：内容一致则视为未变化，否则按修改时间决定方向
+		hash, err := calculateMD5(localPath)
+		if err != nil {
+			return "", err
+		}
+		if etag, ok := singlePartETag(remote.ETag); ok && etag == hash {
+			idx.record(relPath, local, hash, remote.ETag, remote.VersionID)
+			return SyncChangeNone, nil
+		}
+		if local.ModTime().After(remote.LastModified) {
+			return SyncChangeLocal, nil
+		}
+		return SyncChangeRemote, nil
+	}
+
+	localChanged := local == nil
+	if local != nil {
+		changed, _, err := idx.localChanged(relPath, localPath, local)
+		if err != nil {
+			return "", err
+		}
+		localChanged = changed
+	}
+
+	remoteChanged := remote == nil || idx.remoteChanged(relPath, *remote)
+
+	switch {
+	case localChanged && remoteChanged:
+		return SyncChangeBoth, nil
+	case localChanged:
+		return SyncChangeLocal, nil
+	case remoteChanged:
+		return SyncChangeRemote, nil
+	default:
+		return SyncChangeNone, nil
+	}
+}
+
+// record 记录文件同步成功后的状态
+func (idx *SyncIndex) record(relPath string, local os.FileInfo, localHash, remoteETag, versionID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.Entries[relPath] = SyncIndexEntry{
+		Path:         relPath,
+		Size:         local.Size(),
+		LocalModTime: local.ModTime(),
+		LocalHash:    localHash,
+		RemoteETag:   remoteETag,
+		VersionID:    versionID,
+		SyncedAt:     time.Now(),
+	}
+}
+
+// forget 从索引中移除文件记录
+func (idx *SyncIndex) forget(relPath string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.Entries, relPath)
+}
+
+// syncRelPath 将本地文件路径转换为索引使用的相对路径
+func syncRelPath(localRoot, localPath string) (string, error) {
+	relPath, err := filepath.Rel(localRoot, localPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+// syncRemoteKey 根据远程根路径和相对路径生成对象键
+func (a *App) syncRemoteKey(remoteRoot, relPath string) string {
+	remotePath := filepath.Join(remoteRoot, relPath)
+	remotePath = strings.ReplaceAll(remotePath, "\\", "/")
+
+	// 加密文件名的规则中，规则根路径之后的部分使用加密后的名称
+	if enc, _ := a.cipherForKey(remotePath); enc != nil && enc.encryptNames {
+		remotePath = enc.prefix + enc.encryptPath(strings.TrimPrefix(remotePath, enc.prefix))
+	}
+	return remotePath
+}
+
+// syncRemoteRelPath 将对象键转换为相对于远程根路径的相对路径
+func (a *App) syncRemoteRelPath(remoteRoot, key string) string {
+	if enc, _ := a.cipherForKey(key); enc != nil && enc.encryptNames {
+		// 无法解密的名称（如其他工具写入的对象）保持原样
+		if name, err := enc.decryptPath(strings.TrimPrefix(key, enc.prefix)); err == nil {
+			key = enc.prefix + name
+		}
+	}
+	relPath := strings.TrimPrefix(key, remoteRoot)
+	return strings.TrimPrefix(relPath, "/")
+}
+
+// planUpload 根据三方比较结果决定是否需要上传本地文件，不需要上传时返回nil
+func (a *App) planUpload(index *SyncIndex, config SyncConfig, localFile string, remoteFileMap map[string]MinioFileInfo) (*transferTask, error) {
+	// 计算相对路径和远程路径
+	relPath, err := syncRelPath(config.LocalPath, localFile)
+	if err != nil {
+		return nil, fmt.Errorf("计算相对路径失败: %v", err)
+	}
+	remotePath := a.syncRemoteKey(config.RemotePath, relPath)
+
+	localInfo, err := os.Stat(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+
+	var remote *MinioFileInfo
+	if remoteFile, exists := remoteFileMap[remotePath]; exists {
+		remote = &remoteFile
+	}
+
+	change, err := index.classify(relPath, localFile, localInfo, remote)
+	if err != nil {
+		return nil, fmt.Errorf("比较文件状态失败: %v", err)
+	}
+
+	// 远程对象不存在时直接上传；否则只有仅本地变化时才上传，两端都变化的交给冲突处理
+	if remote != nil && change != SyncChangeLocal {
+		return nil, nil
+	}
+
+	// 远程已是相同内容时只更新索引
+	if remote != nil {
+		same, err := a.recordIfSame(index, relPath, localFile, localInfo, remote)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			return nil, nil
+		}
+	}
+
+	return &transferTask{
+		Kind:       transferUpload,
+		RelPath:    relPath,
+		LocalPath:  localFile,
+		RemotePath: remotePath,
+		Size:       localInfo.Size(),
+	}, nil
+}
+
+// planDownload 根据三方比较结果决定是否需要下载远程对象，不需要下载时返回nil
+func (a *App) planDownload(index *SyncIndex, config SyncConfig, remoteFile MinioFileInfo) (*transferTask, error) {
+	relPath := a.syncRemoteRelPath(config.RemotePath, remoteFile.Path)
+	localPath := filepath.Join(config.LocalPath, filepath.FromSlash(relPath))
+
+	var localInfo os.FileInfo
+	if info, err := os.Stat(localPath); err == nil {
+		localInfo = info
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+
+	change, err := index.classify(relPath, localPath, localInfo, &remoteFile)
+	if err != nil {
+		return nil, fmt.Errorf("比较文件状态失败: %v", err)
+	}
+
+	// 本地文件不存在时直接下载；否则只有仅远程变化时才下载，两端都变化的交给冲突处理
+	if localInfo != nil && change != SyncChangeRemote {
+		return nil, nil
+	}
+
+	// 本地已是相同内容时只更新索引
+	if localInfo != nil {
+		same, err := a.recordIfSame(index, relPath, localPath, localInfo, &remoteFile)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			return nil, nil
+		}
+	}
+
+	return &transferTask{
+		Kind:       transferDownload,
+		RelPath:    relPath,
+		LocalPath:  localPath,
+		RemotePath: remoteFile.Path,
+		RemoteFile: remoteFile,
+		Size:       remoteFile.Size,
+	}, nil
+}
+
+// recordIfSame 本地文件与远程对象内容一致时记录到索引，返回是否一致
+func (a *App) recordIfSame(index *SyncIndex, relPath, localPath string, localInfo os.FileInfo, remote *MinioFileInfo) (bool, error) {
+	hash, err := calculateMD5(localPath)
+	if err != nil {
+		return false, err
+	}
+
+	same, err := a.sameContent(context.Background(), localPath, hash, remote)
+	if err != nil {
+		return false, fmt.Errorf("比较文件内容失败: %v", err)
+	}
+	if same {
+		index.record(relPath, localInfo, hash, remote.ETag, remote.VersionID)
+	}
+	return same, nil
+}
+
+// uploadAndRecord 上传文件并更新同步索引
+func (a *App) uploadAndRecord(ctx context.Context, index *SyncIndex, relPath, localPath, remotePath string) error {
+	info, localInfo, hash, err := a.uploadFile(ctx, localPath, remotePath)
+	if err != nil {
+		return err
+	}
+
+	index.record(relPath, localInfo, hash, info.ETag, info.VersionID)
+	return nil
+}
+
+// downloadAndRecord 下载远程对象到本地并更新同步索引
+func (a *App) downloadAndRecord(ctx context.Context, index *SyncIndex, relPath string, remoteFile MinioFileInfo, localPath string) error {
+	// 流式下载并原子替换本地文件
+	hash, err := a.downloadFileToPath(ctx, remoteFile.Path, localPath)
+	if err != nil {
+		return err
+	}
+
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+
+	index.record(relPath, localInfo, hash, remoteFile.ETag, remoteFile.VersionID)
+	return nil
+}
+
+// saveSyncIndexQuietly 保存同步索引，失败时仅打印日志
+func (a *App) saveSyncIndexQuietly(index *SyncIndex) {
+	if err := a.saveSyncIndex(index); err != nil {
+		fmt.Printf("保存同步索引失败: %v\n", err)
+	}
+}
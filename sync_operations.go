@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -31,8 +32,23 @@ func (a *App) StartSync() error {
 	// 发送状态更新到前端
 	wailsRuntime.EventsEmit(a.ctx, "sync-status-changed", a.syncEnabled)
 
-	// 启动同步服务
-	go a.syncService()
+	// 启动同步服务，停止时通过上下文取消
+	ctx, cancel := context.WithCancel(context.Background())
+	a.syncMu.Lock()
+	a.serviceCtx = ctx
+	a.serviceCancel = cancel
+	a.syncMu.Unlock()
+	go a.syncService(ctx)
+	go a.scrubService(ctx)
+
+	// 启动文件监控，本地变化合并后立即同步；失败时仍按同步间隔定时扫描
+	// 备份模式按同步间隔生成快照，本地和远程的变化都不直接同步
+	if !a.disableFileWatcher && a.syncMode != "backup" {
+		if err := a.startFileWatcher(ctx); err != nil {
+			fmt.Printf("启动文件监控失败，将只进行定时扫描: %v\n", err)
+		}
+		a.startRemoteWatcher(ctx)
+	}
 
 	fmt.Println("同步服务已启动")
 	return nil
@@ -46,12 +62,12 @@ func (a *App) StopSync() error {
 	}
 
 	// 如果同步未在运行，返回错误
-	if !a.syncRunning {
+	if !a.syncRunning && !a.isSyncActive() {
 		return fmt.Errorf("同步服务未在运行")
 	}
 
-	// 停止同步服务
-	a.syncStopCh <- true
+	// 停止同步服务，并取消正在执行的同步
+	a.cancelSync()
 
 	// 设置同步状态
 	a.syncEnabled = false
@@ -65,23 +81,23 @@ func (a *App) StopSync() error {
 }
 
 // syncService 同步服务主循环
-func (a *App) syncService() {
+func (a *App) syncService(ctx context.Context) {
 	// 创建定时器
 	ticker := time.NewTicker(a.syncInterval)
 	defer ticker.Stop()
 
 	// 立即执行一次同步
-	a.performSync()
+	a.performSync(ctx)
 
 	// 主循环
 	for {
 		select {
 		case <-ticker.C:
-			// 定时执行同步
-			if a.syncEnabled {
-				a.performSync()
+			// 定时执行同步，暂停期间跳过
+			if a.syncEnabled && !a.syncPause.isPaused() {
+				a.performSync(ctx)
 			}
-		case <-a.syncStopCh:
+		case <-ctx.Done():
 			// 收到停止信号
 			fmt.Println("同步服务收到停止信号")
 			return
@@ -90,9 +106,18 @@ func (a *App) syncService() {
 }
 
 // performSync 执行同步操作
-func (a *App) performSync() {
+func (a *App) performSync(parent context.Context) {
+	// 同一时间只执行一次同步
+	ctx, ok := a.beginSyncRun(parent)
+	if !ok {
+		fmt.Println("上一次同步尚未完成，跳过本次同步")
+		return
+	}
+	defer a.endSyncRun()
+
 	fmt.Println("开始执行同步...")
 	startTime := time.Now()
+	a.setSyncProgressStatus("running")
 
 	// 创建同步状态
 	status := SyncStatus{
@@ -112,13 +137,13 @@ func (a *App) performSync() {
 	var err error
 	switch a.syncMode {
 	case "full":
-		err = a.fullSync(&status)
+		err = a.fullSync(ctx, &status)
 	case "selective":
-		err = a.selectiveSync(&status)
+		err = a.selectiveSync(ctx, &status)
 	case "backup":
-		err = a.backupSync(&status)
+		err = a.backupSync(ctx, &status)
 	case "incremental":
-		err = a.incrementalSync(&status)
+		err = a.incrementalSync(ctx, &status)
 	default:
 		err = fmt.Errorf("未知的同步模式: %s", a.syncMode)
 	}
@@ -128,6 +153,15 @@ func (a *App) performSync() {
 	if err != nil {
 		status.Errors = append(status.Errors, err.Error())
 	}
+	switch {
+	case ctx.Err() != nil:
+		status.Errors = append(status.Errors, "同步已取消")
+		a.setSyncProgressStatus("cancelled")
+	case len(status.Errors) > 0:
+		a.setSyncProgressStatus("error")
+	default:
+		a.setSyncProgressStatus("completed")
+	}
 
 	// 记录同步历史
 	duration := time.Since(startTime)
@@ -158,12 +192,17 @@ func (a *App) TriggerManualSync() error {
 	}
 
 	// 如果同步已经在运行，返回错误
-	if a.syncRunning {
+	if a.syncRunning || a.isSyncActive() {
 		return fmt.Errorf("同步服务已在运行")
 	}
 
+	// 暂停期间不开始新的同步
+	if a.syncPause.isPaused() {
+		return fmt.Errorf("同步已暂停")
+	}
+
 	// 执行同步
-	go a.performSync()
+	go a.performSync(context.Background())
 
 	fmt.Println("已触发手动同步")
 	return nil
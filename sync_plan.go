@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 同步计划中的操作类型
+const (
+	PlanActionUpload       = "upload"
+	PlanActionDownload     = "download"
+	PlanActionDeleteRemote = "delete-remote"
+	PlanActionDeleteLocal  = "delete-local"
+	PlanActionRenameRemote = "rename-remote"
+	PlanActionRenameLocal  = "rename-local"
+	PlanActionConflict     = "conflict"
+)
+
+// SyncPlanItem 同步计划中的单个操作
+type SyncPlanItem struct {
+	Action  string `json:"action"`
+	Path    string `json:"path"`    // 相对路径
+	NewPath string `json:"newPath"` // 重命名后的相对路径
+	Size    int64  `json:"size"`
+	Reason  string `json:"reason"`
+}
+
+// SyncPlan 同步规则的预览计划，只比较不传输
+type SyncPlan struct {
+	RuleID          string         `json:"ruleId"`
+	RuleName        string         `json:"ruleName"`
+	Direction       string         `json:"direction"`
+	Mode            string         `json:"mode"`
+	Items           []SyncPlanItem `json:"items"`
+	Uploads         int            `json:"uploads"`
+	Downloads       int            `json:"downloads"`
+	Deletes         int            `json:"deletes"`
+	Renames         int            `json:"renames"`
+	Conflicts       int            `json:"conflicts"`
+	UploadBytes     int64          `json:"uploadBytes"`
+	DownloadBytes   int64          `json:"downloadBytes"`
+	DeletionBlocked string         `json:"deletionBlocked"` // 触发删除保护时的说明，此时本规则不会执行传输
+}
+
+// add 添加计划操作并更新统计
+func (p *SyncPlan) add(item SyncPlanItem) {
+	p.Items = append(p.Items, item)
+	switch item.Action {
+	case PlanActionUpload:
+		p.Uploads++
+		p.UploadBytes += item.Size
+	case PlanActionDownload:
+		p.Downloads++
+		p.DownloadBytes += item.Size
+	case PlanActionDeleteRemote, PlanActionDeleteLocal:
+		p.Deletes++
+	case PlanActionRenameRemote, PlanActionRenameLocal:
+		p.Renames++
+	case PlanActionConflict:
+		p.Conflicts++
+	}
+}
+
+// PreviewSyncPlan 预览同步规则按当前同步模式执行时将进行的操作，不传输任何数据
+func (a *App) PreviewSyncPlan(ruleID string) (SyncPlan, error) {
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return SyncPlan{}, err
+	}
+
+	plan, err := a.planSync(rule, a.syncMode)
+	if err != nil {
+		return SyncPlan{}, err
+	}
+	return *plan, nil
+}
+
+// planSync 使用与实际同步相同的比较逻辑生成同步计划
+// 同步索引只在内存中修改，不会保存
+func (a *App) planSync(rule SyncRule, mode string) (*SyncPlan, error) {
+	config := syncConfigFromRule(rule)
+	plan := &SyncPlan{
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Direction: rule.Direction,
+		Mode:      mode,
+		Items:     []SyncPlanItem{},
+	}
+
+	// 获取本地文件列表
+	var localFiles []string
+	if _, err := os.Stat(config.LocalPath); err == nil {
+		files, err := getAllFiles(config.LocalPath)
+		if err != nil {
+			return nil, fmt.Errorf("获取本地文件列表失败: %v", err)
+		}
+		localFiles = files
+	}
+
+	// 备份模式创建去重快照，只有存储区中还没有的内容需要上传
+	if mode == "backup" {
+		backup, err := a.planBackup(context.Background(), rule)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range backup.Uploads {
+			plan.add(SyncPlanItem{Action: PlanActionUpload, Path: task.RelPath, Size: task.Size, Reason: "备份新内容"})
+		}
+		return plan, nil
+	}
+
+	// 获取远程文件列表，远程路径不存在时视为空
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, fmt.Errorf("获取远程文件列表失败: %v", err)
+	}
+
+	// 创建远程文件映射，用于快速查找
+	remoteFileMap := make(map[string]MinioFileInfo)
+	for _, file := range remoteFiles {
+		if !file.IsDir {
+			remoteFileMap[file.Path] = file
+		}
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重命名和移动，涉及的路径不再参与后续比较
+	renames, err := a.planRenames(config, index, localFiles, remoteFileMap)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]bool)
+	for _, op := range renames {
+		action := PlanActionRenameRemote
+		if op.Target == RenameTargetLocal {
+			action = PlanActionRenameLocal
+		}
+		plan.add(SyncPlanItem{Action: action, Path: op.From, NewPath: op.To, Size: op.Size})
+		renamed[op.From] = true
+		renamed[op.To] = true
+		index.forget(op.From)
+	}
+
+	var filteredLocal []string
+	for _, file := range localFiles {
+		relPath, err := syncRelPath(config.LocalPath, file)
+		if err != nil {
+			return nil, fmt.Errorf("计算相对路径失败: %v", err)
+		}
+		if !renamed[relPath] {
+			filteredLocal = append(filteredLocal, file)
+		}
+	}
+	for key := range remoteFileMap {
+		if renamed[a.syncRemoteRelPath(config.RemotePath, key)] {
+			delete(remoteFileMap, key)
+		}
+	}
+	localFiles = filteredLocal
+
+	// 冲突：两端自上次同步后都有变化
+	if mode != "selective" || rule.Direction == "bidirectional" {
+		if err := a.planConflicts(plan, config, index, localFiles, remoteFileMap); err != nil {
+			return nil, err
+		}
+	}
+
+	// 双向同步传播删除，超过删除保护阈值时整条规则不会执行
+	if rule.Direction == "bidirectional" {
+		deletions, err := a.planDeletions(config, index, localFiles, remoteFileMap)
+		if err != nil {
+			return nil, err
+		}
+		for _, relPath := range deletions.RemoteDeletes {
+			plan.add(SyncPlanItem{Action: PlanActionDeleteRemote, Path: relPath, Size: remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)].Size, Reason: "本地已删除"})
+		}
+		for _, relPath := range deletions.LocalDeletes {
+			plan.add(SyncPlanItem{Action: PlanActionDeleteLocal, Path: relPath, Size: index.Entries[relPath].Size, Reason: "远程已删除"})
+		}
+		if deletions.exceedsThreshold(config.MaxDeletePercent) {
+			plan.DeletionBlocked = fmt.Sprintf("将删除 %d/%d 个文件，超过删除保护阈值 %d%%，本规则不会执行",
+				deletions.Total(), deletions.TrackedFiles, effectiveMaxDeletePercent(config.MaxDeletePercent))
+			return plan, nil
+		}
+	}
+
+	// 选择性同步和增量同步只处理未被过滤的文件
+	filtered := mode == "selective" || mode == "incremental"
+
+	// 上传
+	if rule.Direction == "upload" || rule.Direction == "bidirectional" {
+		for _, file := range localFiles {
+			if filtered && matchesFilter(file, rule.Filters) {
+				continue
+			}
+			task, err := a.planUpload(index, config, file, remoteFileMap)
+			if err != nil {
+				return nil, err
+			}
+			if task == nil {
+				continue
+			}
+			reason := "本地已修改"
+			if _, exists := remoteFileMap[task.RemotePath]; !exists {
+				reason = "远程不存在"
+			}
+			plan.add(SyncPlanItem{Action: PlanActionUpload, Path: task.RelPath, Size: task.Size, Reason: reason})
+		}
+	}
+
+	// 下载
+	if rule.Direction == "download" || rule.Direction == "bidirectional" {
+		var keys []string
+		for key := range remoteFileMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			remoteFile := remoteFileMap[key]
+			if mode == "incremental" && matchesFilter(a.syncRemoteRelPath(config.RemotePath, remoteFile.Path), rule.Filters) {
+				continue
+			}
+			task, err := a.planDownload(index, config, remoteFile)
+			if err != nil {
+				return nil, err
+			}
+			if task == nil {
+				continue
+			}
+			reason := "远程已修改"
+			if _, err := os.Stat(task.LocalPath); os.IsNotExist(err) {
+				reason = "本地不存在"
+			}
+			plan.add(SyncPlanItem{Action: PlanActionDownload, Path: task.RelPath, Size: task.Size, Reason: reason})
+		}
+	}
+
+	return plan, nil
+}
+
+// planConflicts 将两端都有变化的文件加入同步计划
+// 预览时不查询远程对象元数据，内容实际相同的文件在真正同步时不会被视为冲突
+func (a *App) planConflicts(plan *SyncPlan, config SyncConfig, index *SyncIndex, localFiles []string, remoteFileMap map[string]MinioFileInfo) error {
+	for _, localFile := range localFiles {
+		relPath, err := syncRelPath(config.LocalPath, localFile)
+		if err != nil {
+			return fmt.Errorf("计算相对路径失败: %v", err)
+		}
+
+		remoteFile, exists := remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)]
+		if !exists {
+			continue
+		}
+
+		localInfo, err := os.Stat(localFile)
+		if err != nil {
+			return fmt.Errorf("获取本地文件信息失败: %v", err)
+		}
+
+		change, err := index.classify(relPath, localFile, localInfo, &remoteFile)
+		if err != nil {
+			return fmt.Errorf("比较文件状态失败: %v", err)
+		}
+		if change == SyncChangeBoth {
+			plan.add(SyncPlanItem{Action: PlanActionConflict, Path: relPath, Size: localInfo.Size(), Reason: "解决方式: " + a.defaultConflictResolution})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// remotePollInterval 无法使用存储桶通知时轮询远程变化的间隔
+const remotePollInterval = 30 * time.Second
+
+// remoteWatchEvents 需要监听的存储桶事件
+var remoteWatchEvents = []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"}
+
+// remoteWatcher 通过存储桶通知监听规则远程路径下的对象变化，不支持通知时改为轮询
+type remoteWatcher struct {
+	app     *App
+	queue   *changeQueue
+	cancel  context.CancelFunc
+	rules   map[string]SyncRule // 规则ID -> 规则
+	polling map[string]bool     // 已改为轮询的规则
+	mu      sync.Mutex
+}
+
+// startRemoteWatcher 为所有启用的下载和双向同步规则监听远程变化
+func (a *App) startRemoteWatcher(ctx context.Context) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	r := &remoteWatcher{
+		app:     a,
+		queue:   newChangeQueue(a, "remote-watch", a.syncRemoteChangedPaths),
+		cancel:  cancel,
+		rules:   make(map[string]SyncRule),
+		polling: make(map[string]bool),
+	}
+
+	for _, rule := range a.GetSyncRules() {
+		// 只有远程变化需要下载的规则才需要监听
+		if !rule.Enabled || rule.Direction == "upload" {
+			continue
+		}
+		r.rules[rule.ID] = rule
+		go r.listen(watchCtx, rule)
+	}
+
+	a.syncMu.Lock()
+	a.remoteWatcher = r
+	a.syncMu.Unlock()
+
+	fmt.Printf("远程变化监听已启动，共 %d 条规则\n", len(r.rules))
+}
+
+// stopRemoteWatcher 停止远程变化监听
+func (a *App) stopRemoteWatcher() {
+	a.syncMu.Lock()
+	r := a.remoteWatcher
+	a.remoteWatcher = nil
+	a.syncMu.Unlock()
+
+	if r != nil {
+		r.cancel()
+		r.queue.stop()
+	}
+}
+
+// restartRemoteWatcher 同步规则变化后重新建立远程变化监听
+func (a *App) restartRemoteWatcher() {
+	a.syncMu.Lock()
+	running := a.remoteWatcher != nil
+	ctx := a.serviceCtx
+	a.syncMu.Unlock()
+
+	if !running || ctx == nil {
+		return
+	}
+
+	a.stopRemoteWatcher()
+	a.startRemoteWatcher(ctx)
+}
+
+// remoteWatchPrefix 规则远程路径对应的对象前缀
+func remoteWatchPrefix(remotePath string) string {
+	if remotePath != "" && !strings.HasSuffix(remotePath, "/") {
+		return remotePath + "/"
+	}
+	return remotePath
+}
+
+// isSyncManagedKey 判断规则远程根目录 root 下的对象是否为同步的文件
+// 回收站、分块存储、加密密钥描述以及备份模式的快照文件夹和内容存储由各自的功能管理，同步时跳过
+func isSyncManagedKey(root, key string) bool {
+	if isTrashKey(key) || isChunkKey(key) || path.Base(key) == encryptionDescriptorName {
+		return false
+	}
+	first, _, nested := strings.Cut(strings.TrimPrefix(key, root), "/")
+	if !nested {
+		return true
+	}
+	return first != backupDataFolder && !isBackupFolder(first)
+}
+
+// listen 接收规则远程路径下的存储桶通知，出错时改为轮询
+func (r *remoteWatcher) listen(ctx context.Context, rule SyncRule) {
+	a := r.app
+	if a.minioClient == nil {
+		return
+	}
+
+	notifications := a.minioClient.ListenBucketNotification(ctx, a.minioConfig.BucketName, remoteWatchPrefix(rule.RemotePath), "", remoteWatchEvents)
+	for info := range notifications {
+		if info.Err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("规则 '%s' 无法接收存储桶通知，改为每 %s 轮询一次: %v\n", rule.Name, remotePollInterval, info.Err)
+			r.mu.Lock()
+			r.polling[rule.ID] = true
+			r.mu.Unlock()
+			r.poll(ctx, rule)
+			return
+		}
+
+		for _, record := range info.Records {
+			// 通知中的对象键经过URL编码
+			key, err := url.QueryUnescape(record.S3.Object.Key)
+			if err != nil {
+				key = record.S3.Object.Key
+			}
+			r.queue.add(ctx, rule.ID, key)
+		}
+	}
+}
+
+// poll 定期列出远程对象，与上一次的结果比较找出变化
+func (r *remoteWatcher) poll(ctx context.Context, rule SyncRule) {
+	snapshot, _ := r.snapshot(rule)
+
+	ticker := time.NewTicker(remotePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := r.snapshot(rule)
+			if err != nil {
+				fmt.Printf("轮询规则 '%s' 的远程变化失败: %v\n", rule.Name, err)
+				continue
+			}
+
+			// 第一次成功列出时只记录状态
+			if snapshot != nil {
+				for key, etag := range current {
+					if previous, exists := snapshot[key]; !exists || previous != etag {
+						r.queue.add(ctx, rule.ID, key)
+					}
+				}
+				for key := range snapshot {
+					if _, exists := current[key]; !exists {
+						r.queue.add(ctx, rule.ID, key)
+					}
+				}
+			}
+			snapshot = current
+		}
+	}
+}
+
+// snapshot 列出规则远程路径下的对象及其ETag
+func (r *remoteWatcher) snapshot(rule SyncRule) (map[string]string, error) {
+	files, err := r.app.ListMinioFiles(rule.RemotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]string)
+	for _, file := range files {
+		if !file.IsDir {
+			objects[file.Path] = file.ETag
+		}
+	}
+	return objects, nil
+}
+
+// status 将远程监听状态写入文件监控状态
+func (r *remoteWatcher) status(status *FileWatcherStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, rule := range r.rules {
+		if r.polling[id] {
+			status.RemotePollRules = append(status.RemotePollRules, rule.Name)
+		} else {
+			status.RemoteNotifyRules = append(status.RemoteNotifyRules, rule.Name)
+		}
+	}
+	sort.Strings(status.RemoteNotifyRules)
+	sort.Strings(status.RemotePollRules)
+	status.PendingFiles += r.queue.size()
+}
+
+// syncRemoteChangedPaths 只同步远程发生变化的对象
+// 对象消失时可能是删除或重命名，交给完整的重命名识别和删除传播处理，以保留删除保护
+func (a *App) syncRemoteChangedPaths(ctx context.Context, rule SyncRule, keys []string, status *SyncStatus) error {
+	// 备份模式的远程目录只有快照，不同步到本地
+	if a.syncMode == "backup" {
+		return nil
+	}
+
+	config := syncConfigFromRule(rule)
+
+	var changed []MinioFileInfo
+	removed := false
+	for _, key := range keys {
+		// 跳过目录占位对象和不属于同步文件的对象
+		if strings.HasSuffix(key, "/") || !isSyncManagedKey(remoteWatchPrefix(config.RemotePath), key) {
+			continue
+		}
+		if a.syncMode == "incremental" && matchesFilter(a.syncRemoteRelPath(config.RemotePath, key), rule.Filters) {
+			continue
+		}
+
+		info, err := a.GetMinioFileInfo(key)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				removed = true
+				continue
+			}
+			return fmt.Errorf("获取远程文件信息失败: %v", err)
+		}
+		changed = append(changed, info)
+	}
+
+	// 先处理重命名和删除，它们会读写同步索引
+	if removed {
+		if err := a.applyRenames(ctx, config, status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("检测重命名失败: %v", err))
+		}
+		if rule.Direction == "bidirectional" {
+			if err := a.propagateDeletions(ctx, config, status); err != nil {
+				return err
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	var tasks []transferTask
+	for _, remoteFile := range changed {
+		task, err := a.planDownload(index, config, remoteFile)
+		if err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("下载文件失败: %v", err))
+			continue
+		}
+		if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+
+	result := a.runTransfers(ctx, config, index, tasks)
+	status.FilesDownloaded += result.Downloaded
+	status.Errors = append(status.Errors, result.Errors...)
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 重命名操作的执行位置
+const (
+	RenameTargetRemote = "remote" // 本地发生了重命名，在远程执行
+	RenameTargetLocal  = "local"  // 远程发生了重命名，在本地执行
+)
+
+// RenameOp 重命名（移动）操作
+type RenameOp struct {
+	From   string `json:"from"` // 原相对路径
+	To     string `json:"to"`   // 新相对路径
+	Size   int64  `json:"size"`
+	Target string `json:"target"`
+}
+
+// planRenames 根据同步索引识别重命名和移动
+// 本地重命名：索引中的文件本地消失而远程未变，同时出现大小和内容哈希都相同的新本地文件；
+// 远程重命名：索引中的对象远程消失而本地未变，同时出现大小和ETag都相同的新远程对象
+func (a *App) planRenames(config SyncConfig, index *SyncIndex, localFiles []string, remoteFileMap map[string]MinioFileInfo) ([]RenameOp, error) {
+	var ops []RenameOp
+
+	// 创建本地文件集合
+	localSet := make(map[string]string)
+	for _, file := range localFiles {
+		relPath, err := syncRelPath(config.LocalPath, file)
+		if err != nil {
+			return nil, fmt.Errorf("计算相对路径失败: %v", err)
+		}
+		localSet[relPath] = file
+	}
+
+	// 按大小分组记录一端消失的索引条目，排序保证结果稳定
+	localGone := make(map[int64][]string)
+	remoteGone := make(map[int64][]string)
+	var trackedPaths []string
+	for relPath := range index.Entries {
+		trackedPaths = append(trackedPaths, relPath)
+	}
+	sort.Strings(trackedPaths)
+
+	for _, relPath := range trackedPaths {
+		entry := index.Entries[relPath]
+		localFile, localExists := localSet[relPath]
+		remoteFile, remoteExists := remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)]
+
+		if !localExists && remoteExists && !index.remoteChanged(relPath, remoteFile) {
+			localGone[entry.Size] = append(localGone[entry.Size], relPath)
+		}
+
+		if localExists && !remoteExists {
+			localInfo, err := os.Stat(localFile)
+			if err != nil {
+				return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
+			}
+			changed, _, err := index.localChanged(relPath, localFile, localInfo)
+			if err != nil {
+				return nil, fmt.Errorf("比较文件状态失败: %v", err)
+			}
+			if !changed {
+				remoteGone[entry.Size] = append(remoteGone[entry.Size], relPath)
+			}
+		}
+	}
+
+	// 本地重命名，需要在远程执行
+	if config.Direction == "upload" || config.Direction == "bidirectional" {
+		var candidates []string
+		for relPath := range localSet {
+			if _, known := index.Entries[relPath]; known {
+				continue
+			}
+			if _, exists := remoteFileMap[a.syncRemoteKey(config.RemotePath, relPath)]; exists {
+				continue
+			}
+			candidates = append(candidates, relPath)
+		}
+		sort.Strings(candidates)
+
+		for _, relPath := range candidates {
+			localInfo, err := os.Stat(localSet[relPath])
+			if err != nil {
+				return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
+			}
+			group := localGone[localInfo.Size()]
+			if len(group) == 0 {
+				continue
+			}
+
+			// 只有大小匹配时才计算哈希
+			hash, err := calculateMD5(localSet[relPath])
+			if err != nil {
+				return nil, err
+			}
+			for i, from := range group {
+				if index.Entries[from].LocalHash == hash {
+					ops = append(ops, RenameOp{From: from, To: relPath, Size: localInfo.Size(), Target: RenameTargetRemote})
+					localGone[localInfo.Size()] = append(group[:i:i], group[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	// 远程重命名，需要在本地执行
+	if config.Direction == "download" || config.Direction == "bidirectional" {
+		var candidates []MinioFileInfo
+		for _, remoteFile := range remoteFileMap {
+			relPath := a.syncRemoteRelPath(config.RemotePath, remoteFile.Path)
+			if _, known := index.Entries[relPath]; known {
+				continue
+			}
+			if _, exists := localSet[relPath]; exists {
+				continue
+			}
+			candidates = append(candidates, remoteFile)
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Path < candidates[j].Path })
+
+		for _, remoteFile := range candidates {
+			group := remoteGone[remoteFile.Size]
+			for i, from := range group {
+				if index.Entries[from].RemoteETag == remoteFile.ETag {
+					to := a.syncRemoteRelPath(config.RemotePath, remoteFile.Path)
+					ops = append(ops, RenameOp{From: from, To: to, Size: remoteFile.Size, Target: RenameTargetLocal})
+					remoteGone[remoteFile.Size] = append(group[:i:i], group[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return ops, nil
+}
+
+// applyRenames 识别并执行规则内的重命名和移动，避免删除后重新传输
+func (a *App) applyRenames(ctx context.Context, config SyncConfig, status *SyncStatus) error {
+	// 检查本地路径是否存在
+	if _, err := os.Stat(config.LocalPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	// 获取本地文件列表
+	localFiles, err := getAllFiles(config.LocalPath)
+	if err != nil {
+		return fmt.Errorf("获取本地文件列表失败: %v", err)
+	}
+
+	// 获取远程文件列表
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
+	if err != nil {
+		return fmt.Errorf("获取远程文件列表失败: %v", err)
+	}
+
+	// 创建远程文件映射，用于快速查找
+	remoteFileMap := make(map[string]MinioFileInfo)
+	for _, file := range remoteFiles {
+		if !file.IsDir {
+			remoteFileMap[file.Path] = file
+		}
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	ops, err := a.planRenames(config, index, localFiles, remoteFileMap)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		switch op.Target {
+		case RenameTargetRemote:
+			err = a.renameRemote(ctx, config, index, op)
+		case RenameTargetLocal:
+			err = a.renameLocal(config, index, op, remoteFileMap[a.syncRemoteKey(config.RemotePath, op.To)])
+		}
+		if err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("重命名 %s -> %s 失败: %v", op.From, op.To, err))
+		}
+	}
+
+	return nil
+}
+
+// renameRemote 在远程执行本地发生的重命名
+func (a *App) renameRemote(ctx context.Context, config SyncConfig, index *SyncIndex, op RenameOp) error {
+	from := a.syncRemoteKey(config.RemotePath, op.From)
+	to := a.syncRemoteKey(config.RemotePath, op.To)
+	fmt.Printf("远程重命名: %s -> %s\n", from, to)
+
+	info, err := a.moveMinioObject(ctx, from, to, op.Size)
+	if err != nil {
+		return err
+	}
+
+	localInfo, err := os.Stat(filepath.Join(config.LocalPath, filepath.FromSlash(op.To)))
+	if err != nil {
+		return fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+
+	entry := index.Entries[op.From]
+	index.forget(op.From)
+	index.record(op.To, localInfo, entry.LocalHash, info.ETag, info.VersionID)
+	return nil
+}
+
+// renameLocal 在本地执行远程发生的重命名
+func (a *App) renameLocal(config SyncConfig, index *SyncIndex, op RenameOp, remoteFile MinioFileInfo) error {
+	from := filepath.Join(config.LocalPath, filepath.FromSlash(op.From))
+	to := filepath.Join(config.LocalPath, filepath.FromSlash(op.To))
+	fmt.Printf("本地重命名: %s -> %s\n", from, to)
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("创建本地目录失败: %v", err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("重命名本地文件失败: %v", err)
+	}
+	removeEmptyParents(filepath.Dir(from), config.LocalPath)
+
+	localInfo, err := os.Stat(to)
+	if err != nil {
+		return fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+
+	entry := index.Entries[op.From]
+	index.forget(op.From)
+	index.record(op.To, localInfo, entry.LocalHash, remoteFile.ETag, remoteFile.VersionID)
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTransferConcurrency 允许配置的最大并发传输数
+	maxTransferConcurrency = 64
+	// largeFileThreshold 超过该大小的文件单独调度并优先传输
+	largeFileThreshold = 8 * 1024 * 1024
+	// smallFileBatchCount 每批小文件的最大数量
+	smallFileBatchCount = 32
+	// smallFileBatchBytes 每批小文件的最大总字节数
+	smallFileBatchBytes = 8 * 1024 * 1024
+)
+
+// 传输方向
+const (
+	transferUpload   = "upload"
+	transferDownload = "download"
+)
+
+// transferTask 单个文件的传输任务
+type transferTask struct {
+	Kind       string
+	RelPath    string
+	LocalPath  string
+	RemotePath string
+	RemoteFile MinioFileInfo // 下载任务对应的远程对象
+	Size       int64
+	Checksum   string      // 备份内容寻址时上传内容应有的SHA-256
+	ModTime    time.Time   // 恢复快照时还原的修改时间
+	Mode       os.FileMode // 恢复快照时还原的权限
+}
+
+// transferResult 一组传输任务的执行结果
+type transferResult struct {
+	Uploaded   int
+	Downloaded int
+	Errors     []string
+}
+
+// err 将传输失败汇总为一个错误，全部成功时返回nil
+func (r transferResult) err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d 个文件传输失败: %s", len(r.Errors), r.Errors[0])
+}
+
+// defaultTransferConcurrency 根据CPU数量计算默认并发传输数
+func defaultTransferConcurrency() int {
+	concurrency := runtime.NumCPU()
+	if concurrency > 4 {
+		concurrency = 4 // 最大并发数限制为4
+	}
+	return concurrency
+}
+
+// globalTransferConcurrency 获取全局并发传输数：优先使用配置值，其次是性能优化结果
+func (a *App) globalTransferConcurrency() int {
+	if a.syncMaxConcurrency > 0 {
+		return a.syncMaxConcurrency
+	}
+	if a.transferConcurrency > 0 {
+		return a.transferConcurrency
+	}
+	return defaultTransferConcurrency()
+}
+
+// transferLimiter 获取全局传输并发槽位，并发数变化时重新创建
+func (a *App) transferLimiter() chan struct{} {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+
+	limit := a.globalTransferConcurrency()
+	if a.transferSlots == nil || cap(a.transferSlots) != limit {
+		a.transferSlots = make(chan struct{}, limit)
+	}
+	return a.transferSlots
+}
+
+// SetMaxSyncConcurrency 设置全局最大并发传输数，0 表示根据系统资源自动选择
+func (a *App) SetMaxSyncConcurrency(maxConcurrency int) error {
+	if maxConcurrency < 0 || maxConcurrency > maxTransferConcurrency {
+		return fmt.Errorf("并发传输数必须在0到%d之间: %d", maxTransferConcurrency, maxConcurrency)
+	}
+
+	a.transferMu.Lock()
+	a.syncMaxConcurrency = maxConcurrency
+	a.transferMu.Unlock()
+
+	return a.saveConfig()
+}
+
+// scheduleTransfers 将传输任务编排为批次
+// 大文件按大小降序单独成批并优先执行，避免最后只剩一个大文件在传输；
+// 小文件按路径顺序合并成批，减少调度开销
+func scheduleTransfers(tasks []transferTask) [][]transferTask {
+	var large, small []transferTask
+	for _, task := range tasks {
+		if task.Size >= largeFileThreshold {
+			large = append(large, task)
+		} else {
+			small = append(small, task)
+		}
+	}
+	sort.SliceStable(large, func(i, j int) bool { return large[i].Size > large[j].Size })
+	sort.SliceStable(small, func(i, j int) bool { return small[i].RelPath < small[j].RelPath })
+
+	batches := make([][]transferTask, 0, len(large)+len(small)/smallFileBatchCount+1)
+	for _, task := range large {
+		batches = append(batches, []transferTask{task})
+	}
+
+	var batch []transferTask
+	var batchBytes int64
+	for _, task := range small {
+		if len(batch) > 0 && (len(batch) >= smallFileBatchCount || batchBytes+task.Size > smallFileBatchBytes) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+		batch = append(batch, task)
+		batchBytes += task.Size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// runTransfers 使用工作池并发执行传输任务
+// 每条规则的工作协程数由规则并发数决定，同时受所有规则共享的全局并发槽位限制；
+// 同步暂停时工作协程在任务之间等待，取消时中断进行中的传输并放弃剩余任务
+func (a *App) runTransfers(ctx context.Context, config SyncConfig, index *SyncIndex, tasks []transferTask) transferResult {
+	var result transferResult
+	if len(tasks) == 0 {
+		return result
+	}
+
+	batches := scheduleTransfers(tasks)
+	slots := a.transferLimiter()
+
+	workers := config.Concurrency
+	if workers <= 0 {
+		workers = cap(slots)
+	}
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+
+	jobs := make(chan []transferTask)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				// 取消后丢弃剩余任务
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					continue
+				}
+				for _, task := range batch {
+					// 暂停时在任务之间等待，队列保持不变
+					if a.syncCheckpoint(ctx) != nil {
+						break
+					}
+					err := a.runTransfer(ctx, index, task)
+					if ctx.Err() != nil {
+						break
+					}
+
+					mu.Lock()
+					switch {
+					case err != nil && task.Kind == transferUpload:
+						result.Errors = append(result.Errors, fmt.Sprintf("上传文件失败: %v", err))
+					case err != nil:
+						result.Errors = append(result.Errors, fmt.Sprintf("下载文件失败: %v", err))
+					case task.Kind == transferUpload:
+						result.Uploaded++
+					default:
+						result.Downloaded++
+					}
+					mu.Unlock()
+				}
+				<-slots
+			}
+		}()
+	}
+
+	for _, batch := range batches {
+		jobs <- batch
+	}
+	close(jobs)
+	wg.Wait()
+
+	return result
+}
+
+// runTransfer 执行单个传输任务并更新同步索引
+func (a *App) runTransfer(ctx context.Context, index *SyncIndex, task transferTask) error {
+	switch task.Kind {
+	case transferUpload:
+		fmt.Printf("上传文件: %s -> %s\n", task.LocalPath, task.RemotePath)
+		// 没有同步索引时只传输不记录，用于备份
+		if index == nil {
+			return a.uploadContent(ctx, task)
+		}
+		return a.uploadAndRecord(ctx, index, task.RelPath, task.LocalPath, task.RemotePath)
+	case transferDownload:
+		fmt.Printf("下载文件: %s -> %s\n", task.RemotePath, task.LocalPath)
+		if index == nil {
+			return a.restoreContent(ctx, task)
+		}
+		return a.downloadAndRecord(ctx, index, task.RelPath, task.RemoteFile, task.LocalPath)
+	default:
+		return fmt.Errorf("无效的传输类型: %s", task.Kind)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -73,6 +74,15 @@ func (a *App) IsLocalFileNewer(localPath string, remotePath string) (bool, error
 		return true, nil // 远程文件不存在，认为本地文件更新
 	}
 	
+	// 内容相同时无需上传，与修改时间无关
+	same, err := a.sameContent(context.Background(), localPath, "", &remoteInfo)
+	if err != nil {
+		return false, err
+	}
+	if same {
+		return false, nil
+	}
+	
 	return localModTime.After(remoteInfo.LastModified), nil
 }
 
@@ -89,6 +99,15 @@ func (a *App) IsRemoteFileNewer(remotePath string, localPath string) (bool, erro
 		return true, nil // 本地文件不存在，认为远程文件更新
 	}
 	
+	// 内容相同时无需下载，与修改时间无关
+	same, err := a.sameContent(context.Background(), localPath, "", &remoteInfo)
+	if err != nil {
+		return false, err
+	}
+	if same {
+		return false, nil
+	}
+	
 	// 获取本地文件的修改时间
 	localModTime, err := getFileModTime(localPath)
 	if err != nil {
@@ -326,6 +345,31 @@ func (a *App) ValidateSyncRule(rule SyncRule) error {
 		return fmt.Errorf("无效的同步方向: %s", rule.Direction)
 	}
 	
+	// 检查删除保护阈值
+	if rule.MaxDeletePercent < 0 || rule.MaxDeletePercent > 100 {
+		return fmt.Errorf("删除保护阈值必须在0到100之间: %d", rule.MaxDeletePercent)
+	}
+	
+	// 检查并发传输数
+	if rule.Concurrency < 0 || rule.Concurrency > maxTransferConcurrency {
+		return fmt.Errorf("并发传输数必须在0到%d之间: %d", maxTransferConcurrency, rule.Concurrency)
+	}
+	
+	// 检查备份保留策略
+	if err := rule.Retention.validate(); err != nil {
+		return err
+	}
+
+	// 检查压缩设置
+	if err := rule.Compression.validate(); err != nil {
+		return err
+	}
+
+	// 检查分块存储设置
+	if err := rule.Chunking.validate(); err != nil {
+		return err
+	}
+	
 	return nil
 }
 
@@ -371,6 +415,24 @@ func (a *App) GetSyncRuleByName(name string) (SyncRule, error) {
 	return SyncRule{}, fmt.Errorf("未找到同步规则: %s", name)
 }
 
+// ruleForKey 查找远程路径包含该对象键且满足条件的同步规则，多条规则都匹配时使用远程路径最长的规则
+func (a *App) ruleForKey(key string, match func(SyncRule) bool) (SyncRule, bool) {
+	var found *SyncRule
+	for i := range a.syncRules {
+		rule := &a.syncRules[i]
+		if !match(*rule) || !strings.HasPrefix(key, remoteWatchPrefix(rule.RemotePath)) {
+			continue
+		}
+		if found == nil || len(rule.RemotePath) > len(found.RemotePath) {
+			found = rule
+		}
+	}
+	if found == nil {
+		return SyncRule{}, false
+	}
+	return *found, true
+}
+
 // GetEnabledSyncRules 获取已启用的同步规则
 func (a *App) GetEnabledSyncRules() []SyncRule {
 	var rules []SyncRule
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// 完整性检查发现的问题类型
+const (
+	VerifyStatusMismatch      = "mismatch"       // 本地与远程内容不一致
+	VerifyStatusLocalCorrupt  = "local-corrupt"  // 本地文件内容变化但大小和修改时间未变（位衰减）
+	VerifyStatusRemoteCorrupt = "remote-corrupt" // 远程对象内容与其校验和不一致
+	VerifyStatusMissingLocal  = "missing-local"  // 本地文件不存在
+	VerifyStatusMissingRemote = "missing-remote" // 远程对象不存在
+	VerifyStatusUnverified    = "unverified"     // 没有可用的校验和，无法比较
+	VerifyStatusError         = "error"          // 检查过程出错
+)
+
+// 完整性检查的修复方向
+const (
+	VerifyRepairNone     = ""
+	VerifyRepairUpload   = "upload"   // 以本地文件为准
+	VerifyRepairDownload = "download" // 以远程对象为准
+)
+
+// VerifyIssue 完整性检查发现的单个问题
+type VerifyIssue struct {
+	Path        string `json:"path"` // 相对路径
+	Status      string `json:"status"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repairError"`
+}
+
+// VerifyReport 单条同步规则的完整性检查报告
+type VerifyReport struct {
+	RuleID     string        `json:"ruleId"`
+	RuleName   string        `json:"ruleName"`
+	Repair     string        `json:"repair"`
+	Deep       bool          `json:"deep"` // 是否完整读取远程对象
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Checked    int           `json:"checked"`
+	OK         int           `json:"ok"`
+	Repaired   int           `json:"repaired"`
+	Issues     []VerifyIssue `json:"issues"`
+}
+
+// VerifySyncRule 检查同步规则下本地文件与远程对象的完整性
+// repair 为 upload 或 download 时按指定方向修复发现的问题；deep 为 true 时完整读取远程对象，检查远程数据是否损坏
+func (a *App) VerifySyncRule(ruleID, repair string, deep bool) (VerifyReport, error) {
+	if repair != VerifyRepairNone && repair != VerifyRepairUpload && repair != VerifyRepairDownload {
+		return VerifyReport{}, fmt.Errorf("无效的修复方向: %s", repair)
+	}
+	// 备份模式的远程路径下是快照和内容存储，与本地文件不是一一对应
+	if a.syncMode == "backup" {
+		return VerifyReport{}, fmt.Errorf("备份模式下不能检查同步文件的完整性")
+	}
+
+	rule, err := a.GetSyncRuleByID(ruleID)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	// 与同步互斥，避免同时修改同步索引
+	ctx, ok := a.beginSyncRun(context.Background())
+	if !ok {
+		return VerifyReport{}, fmt.Errorf("已有同步正在执行，请稍后再试")
+	}
+	defer a.endSyncRun()
+
+	report, err := a.verifyRule(ctx, rule, repair, deep)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	return *report, nil
+}
+
+// GetVerifyReport 获取同步规则最近一次完整性检查的报告
+func (a *App) GetVerifyReport(ruleID string) (VerifyReport, error) {
+	data, err := os.ReadFile(a.verifyReportPath(ruleID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return VerifyReport{}, fmt.Errorf("规则尚未进行完整性检查")
+		}
+		return VerifyReport{}, fmt.Errorf("读取检查报告失败: %v", err)
+	}
+
+	var report VerifyReport
+	if err := a.jsonParser.Unmarshal(data, &report); err != nil {
+		return VerifyReport{}, fmt.Errorf("解析检查报告失败: %v", err)
+	}
+	return report, nil
+}
+
+// SetTransferVerification 设置是否在每次传输后校验内容
+func (a *App) SetTransferVerification(enabled bool) error {
+	a.verifyTransfers = enabled
+	return a.saveConfig()
+}
+
+// SetScrubInterval 设置定期完整性检查的间隔（小时），0 表示不检查
+func (a *App) SetScrubInterval(hours int) error {
+	if hours < 0 {
+		return fmt.Errorf("检查间隔不能为负数")
+	}
+	a.scrubInterval = time.Duration(hours) * time.Hour
+	return a.saveConfig()
+}
+
+// verifyReportPath 获取检查报告文件路径
+func (a *App) verifyReportPath(ruleID string) string {
+	return filepath.Join(a.configDir, "verify", ruleID+".json")
+}
+
+// saveVerifyReport 保存检查报告，每条规则只保留最近一次
+func (a *App) saveVerifyReport(report *VerifyReport) error {
+	reportPath := a.verifyReportPath(report.RuleID)
+	if err := os.MkdirAll(filepath.Dir(reportPath), 0755); err != nil {
+		return fmt.Errorf("创建检查报告目录失败: %v", err)
+	}
+
+	data, err := a.jsonParser.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("序列化检查报告失败: %v", err)
+	}
+	return os.WriteFile(reportPath, data, 0644)
+}
+
+// verifyRule 逐个比较规则下的本地文件和远程对象
+func (a *App) verifyRule(ctx context.Context, rule SyncRule, repair string, deep bool) (*VerifyReport, error) {
+	config := syncConfigFromRule(rule)
+	report := &VerifyReport{
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		Repair:    repair,
+		Deep:      deep,
+		StartedAt: time.Now(),
+		Issues:    []VerifyIssue{},
+	}
+
+	// 本地文件，相对路径 -> 本地路径
+	localFiles := make(map[string]string)
+	if _, err := os.Stat(config.LocalPath); err == nil {
+		files, err := getAllFiles(config.LocalPath)
+		if err != nil {
+			return nil, fmt.Errorf("获取本地文件列表失败: %v", err)
+		}
+		for _, file := range files {
+			if matchesFilter(file, rule.Filters) {
+				continue
+			}
+			relPath, err := syncRelPath(config.LocalPath, file)
+			if err != nil {
+				return nil, fmt.Errorf("计算相对路径失败: %v", err)
+			}
+			localFiles[relPath] = file
+		}
+	}
+
+	// 远程对象，相对路径 -> 对象信息
+	remoteFiles, err := a.ListMinioFiles(config.RemotePath)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, fmt.Errorf("获取远程文件列表失败: %v", err)
+	}
+	remoteMap := make(map[string]MinioFileInfo)
+	for _, file := range remoteFiles {
+		if file.IsDir || matchesFilter(file.Path, rule.Filters) {
+			continue
+		}
+		remoteMap[a.syncRemoteRelPath(config.RemotePath, file.Path)] = file
+	}
+
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for relPath := range localFiles {
+		paths = append(paths, relPath)
+	}
+	for relPath := range remoteMap {
+		if _, exists := localFiles[relPath]; !exists {
+			paths = append(paths, relPath)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, relPath := range paths {
+		if err := a.syncCheckpoint(ctx); err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		localPath := localFiles[relPath]
+		var remote *MinioFileInfo
+		if remoteFile, exists := remoteMap[relPath]; exists {
+			remote = &remoteFile
+		}
+
+		issue, err := a.verifyPath(ctx, index, relPath, localPath, remote, deep)
+		if err != nil {
+			issue = &VerifyIssue{Path: relPath, Status: VerifyStatusError, Detail: err.Error()}
+		}
+		if issue == nil {
+			report.OK++
+			continue
+		}
+
+		if repair != VerifyRepairNone {
+			a.repairIssue(ctx, index, config, issue, localPath, remote, repair)
+			if issue.Repaired {
+				report.Repaired++
+			}
+		}
+		report.Issues = append(report.Issues, *issue)
+	}
+
+	if report.Repaired > 0 {
+		a.saveSyncIndexQuietly(index)
+	}
+
+	report.FinishedAt = time.Now()
+	if err := a.saveVerifyReport(report); err != nil {
+		fmt.Printf("保存检查报告失败: %v\n", err)
+	}
+	return report, nil
+}
+
+// verifyPath 检查单个文件，没有问题时返回nil
+func (a *App) verifyPath(ctx context.Context, index *SyncIndex, relPath, localPath string, remote *MinioFileInfo, deep bool) (*VerifyIssue, error) {
+	if localPath == "" {
+		return &VerifyIssue{Path: relPath, Status: VerifyStatusMissingLocal, Detail: "本地文件不存在"}, nil
+	}
+	if remote == nil {
+		return &VerifyIssue{Path: relPath, Status: VerifyStatusMissingRemote, Detail: "远程对象不存在"}, nil
+	}
+
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("获取本地文件信息失败: %v", err)
+	}
+	localMD5, localSHA, err := calculateChecksums(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 大小和修改时间与上次同步时一致，内容却不同，说明本地数据损坏
+	entry, synced := index.Entries[relPath]
+	localUnchanged := synced && localInfo.Size() == entry.Size && localInfo.ModTime().Equal(entry.LocalModTime)
+	if localUnchanged && entry.LocalHash != "" && localMD5 != entry.LocalHash {
+		return &VerifyIssue{Path: relPath, Status: VerifyStatusLocalCorrupt, Detail: "本地文件内容已变化但修改时间未变，可能发生位衰减"}, nil
+	}
+
+	// 列表结果不包含元数据，重新查询对象信息
+	info, err := a.GetMinioFileInfo(remote.Path)
+	if err != nil {
+		return nil, fmt.Errorf("获取远程文件信息失败: %v", err)
+	}
+	remoteSHA := info.SHA256
+	etag, singlePart := singlePartETag(info.ETag)
+
+	// 加密对象的元数据中保存的是带密钥的校验和
+	enc, err := a.cipherForKey(remote.Path)
+	if err != nil {
+		return nil, err
+	}
+	matches := func(checksum string) bool {
+		return checksum == remoteSHA || (enc != nil && enc.checksum(checksum) == remoteSHA)
+	}
+
+	// 完整读取远程对象，与对象自身的校验和比较
+	if deep {
+		contentMD5, contentSHA, err := a.remoteChecksums(ctx, remote.Path)
+		if err != nil {
+			return nil, err
+		}
+		if remoteSHA != "" && !matches(contentSHA) {
+			return &VerifyIssue{Path: relPath, Status: VerifyStatusRemoteCorrupt, Detail: "远程对象内容与校验和元数据不一致"}, nil
+		}
+		if remoteSHA == "" && singlePart && contentMD5 != etag {
+			return &VerifyIssue{Path: relPath, Status: VerifyStatusRemoteCorrupt, Detail: "远程对象内容与ETag不一致"}, nil
+		}
+		remoteSHA = contentSHA
+	}
+
+	var same bool
+	switch {
+	case remoteSHA != "":
+		same = matches(localSHA)
+	case singlePart:
+		same = etag == localMD5
+	default:
+		return &VerifyIssue{Path: relPath, Status: VerifyStatusUnverified, Detail: "远程对象没有校验和元数据，需要完整读取才能比较"}, nil
+	}
+	if same {
+		return nil, nil
+	}
+
+	detail := "本地与远程内容不一致，可能有尚未同步的修改"
+	if localUnchanged && info.ETag == entry.RemoteETag {
+		detail = "上次同步后两端都未修改，内容却不一致"
+	}
+	return &VerifyIssue{Path: relPath, Status: VerifyStatusMismatch, Detail: detail}, nil
+}
+
+// repairIssue 按指定方向修复问题，不会用已损坏的一端覆盖另一端
+func (a *App) repairIssue(ctx context.Context, index *SyncIndex, config SyncConfig, issue *VerifyIssue, localPath string, remote *MinioFileInfo, repair string) {
+	var err error
+	switch {
+	case repair == VerifyRepairUpload && localPath != "" &&
+		(issue.Status == VerifyStatusMismatch || issue.Status == VerifyStatusMissingRemote || issue.Status == VerifyStatusRemoteCorrupt):
+		err = a.uploadAndRecord(ctx, index, issue.Path, localPath, a.syncRemoteKey(config.RemotePath, issue.Path))
+	case repair == VerifyRepairDownload && remote != nil &&
+		(issue.Status == VerifyStatusMismatch || issue.Status == VerifyStatusMissingLocal || issue.Status == VerifyStatusLocalCorrupt):
+		err = a.downloadAndRecord(ctx, index, issue.Path, *remote, filepath.Join(config.LocalPath, filepath.FromSlash(issue.Path)))
+	default:
+		return
+	}
+
+	if err != nil {
+		issue.RepairError = err.Error()
+		return
+	}
+	issue.Repaired = true
+}
+
+// remoteChecksums 完整读取远程对象，计算MD5和SHA-256，加密对象计算的是解密后的内容
+func (a *App) remoteChecksums(ctx context.Context, remotePath string) (string, string, error) {
+	content, _, _, err := a.openObject(ctx, remotePath, "", "")
+	if err != nil {
+		return "", "", fmt.Errorf("获取对象失败: %v", err)
+	}
+	defer content.Close()
+
+	md5Hash, shaHash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, shaHash), content); err != nil {
+		return "", "", fmt.Errorf("读取对象内容失败: %v", err)
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(shaHash.Sum(nil)), nil
+}
+
+// verifyUploaded 上传后校验远程对象：大小、校验和元数据以及服务端计算的ETag都必须与本地文件一致
+// size 为加密前上传内容的大小，压缩时为压缩后的大小
+func (a *App) verifyUploaded(ctx context.Context, localPath, remotePath string, size int64, checksum string) error {
+	// 加密对象的大小和校验和元数据都由加密前的内容换算
+	enc, err := a.cipherForKey(remotePath)
+	if err != nil {
+		return fmt.Errorf("上传校验失败: %v", err)
+	}
+	if enc != nil {
+		size = enc.encryptedSize(size)
+		checksum = enc.checksum(checksum)
+	}
+
+	info, err := a.statObject(ctx, remotePath, "")
+	if err != nil {
+		return fmt.Errorf("上传校验失败: %v", err)
+	}
+	if info.Size != size {
+		return fmt.Errorf("上传校验失败: 远程大小 %d 与本地大小 %d 不一致", info.Size, size)
+	}
+	if stored := objectChecksum(info.UserMetadata); stored != "" && stored != checksum {
+		return fmt.Errorf("上传校验失败: 校验和元数据不一致")
+	}
+	// 加密、压缩或分块存储的内容与本地文件不同，ETag无法由本地文件计算
+	if enc != nil || metadataValue(info.UserMetadata, compressionMetadataKey) != "" || isChunked(info) {
+		return nil
+	}
+
+	expected, ok, err := expectedETag(localPath, size, info.ETag)
+	if err != nil {
+		return fmt.Errorf("上传校验失败: %v", err)
+	}
+	if ok && expected != strings.ToLower(strings.Trim(info.ETag, "\"")) {
+		return fmt.Errorf("上传校验失败: 服务端收到的内容与本地文件不一致")
+	}
+	return nil
+}
+
+// expectedETag 根据本地文件计算对象应有的ETag
+// 分片上传的ETag是各分片MD5拼接后的MD5加“-分片数”，分片大小与上传时一致才能计算，否则返回false
+func expectedETag(localPath string, size int64, etag string) (string, bool, error) {
+	etag = strings.Trim(etag, "\"")
+	dash := strings.LastIndex(etag, "-")
+	if dash < 0 {
+		hash, err := calculateMD5(localPath)
+		return hash, err == nil, err
+	}
+
+	parts, err := strconv.ParseInt(etag[dash+1:], 10, 64)
+	if err != nil || parts <= 0 {
+		return "", false, nil
+	}
+	partSize := int64(resumablePartSize)
+	if (size+partSize-1)/partSize != parts {
+		return "", false, nil
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", false, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	combined := md5.New()
+	for offset := int64(0); offset < size; offset += partSize {
+		partHash := md5.New()
+		if _, err := io.Copy(partHash, io.NewSectionReader(file, offset, partSize)); err != nil {
+			return "", false, fmt.Errorf("计算哈希值失败: %v", err)
+		}
+		combined.Write(partHash.Sum(nil))
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(combined.Sum(nil)), parts), true, nil
+}
+
+// verifyDownloaded 下载后校验内容：优先与校验和元数据比较，其次与单分片ETag比较
+// enc 为解密使用的加密器，加密对象的元数据中保存的是带密钥的校验和
+func verifyDownloaded(objInfo minio.ObjectInfo, enc *ruleCipher, contentMD5, contentSHA string) error {
+	if enc != nil {
+		contentSHA = enc.checksum(contentSHA)
+	}
+	if checksum := objectChecksum(objInfo.UserMetadata); checksum != "" {
+		if checksum != contentSHA {
+			return fmt.Errorf("下载校验失败: 内容与校验和元数据不一致")
+		}
+		return nil
+	}
+	if etag, ok := singlePartETag(objInfo.ETag); ok && etag != contentMD5 {
+		return fmt.Errorf("下载校验失败: 内容与ETag不一致")
+	}
+	return nil
+}
+
+// scrubService 按设置的间隔定期检查所有启用规则的完整性，只报告问题不修复
+func (a *App) scrubService(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	lastScrub := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if a.scrubInterval <= 0 || time.Since(lastScrub) < a.scrubInterval || a.syncPause.isPaused() {
+			continue
+		}
+		// 备份模式没有与本地一一对应的远程文件，不检查
+		if a.syncMode == "backup" {
+			continue
+		}
+
+		// 有同步正在执行时下一分钟再试
+		runCtx, ok := a.beginSyncRun(ctx)
+		if !ok {
+			continue
+		}
+		lastScrub = time.Now()
+
+		for _, rule := range a.GetSyncRules() {
+			if !rule.Enabled {
+				continue
+			}
+			report, err := a.verifyRule(runCtx, rule, VerifyRepairNone, false)
+			if runCtx.Err() != nil {
+				break
+			}
+			if err != nil {
+				fmt.Printf("规则 '%s' 完整性检查失败: %v\n", rule.Name, err)
+				continue
+			}
+			fmt.Printf("规则 '%s' 完整性检查完成: 检查 %d 个文件, 发现 %d 个问题\n", rule.Name, report.Checked, len(report.Issues))
+		}
+		a.endSyncRun()
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/minio/minio-go/v7"
+)
+
+// watcherDebounce 文件变化事件的合并等待时间，期间的连续变化只触发一次同步
+const watcherDebounce = 2 * time.Second
+
+// FileWatcherStatus 文件监控状态
+type FileWatcherStatus struct {
+	Enabled       bool     `json:"enabled"`
+	Running       bool     `json:"running"`
+	WatchedDirs   int      `json:"watchedDirs"`
+	WatchedRules  []string `json:"watchedRules"`
+	ScanOnlyRules []string `json:"scanOnlyRules"` // 监控数量达到系统上限，只能依靠定时扫描的规则
+	PendingFiles  int      `json:"pendingFiles"`
+	// 远程变化监听
+	RemoteNotifyRules []string `json:"remoteNotifyRules"` // 通过存储桶通知监听的规则
+	RemotePollRules   []string `json:"remotePollRules"`   // 不支持通知，改为轮询的规则
+}
+
+// changeQueue 合并一段时间内的变化路径，等待结束后统一执行针对这些路径的同步
+type changeQueue struct {
+	app     *App
+	mode    string // 同步历史中记录的同步模式
+	apply   func(ctx context.Context, rule SyncRule, paths []string, status *SyncStatus) error
+	pending map[string]map[string]bool // 规则ID -> 变化的路径
+	timer   *time.Timer
+	mu      sync.Mutex
+}
+
+// newChangeQueue 创建变化队列，apply 负责同步单条规则中变化的路径
+func newChangeQueue(app *App, mode string, apply func(ctx context.Context, rule SyncRule, paths []string, status *SyncStatus) error) *changeQueue {
+	return &changeQueue{
+		app:     app,
+		mode:    mode,
+		apply:   apply,
+		pending: make(map[string]map[string]bool),
+	}
+}
+
+// add 加入变化的路径，并重新开始合并等待
+func (q *changeQueue) add(ctx context.Context, ruleID, path string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending[ruleID] == nil {
+		q.pending[ruleID] = make(map[string]bool)
+	}
+	q.pending[ruleID][path] = true
+	q.schedule(ctx)
+}
+
+// size 等待同步的路径数量
+func (q *changeQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	total := 0
+	for _, paths := range q.pending {
+		total += len(paths)
+	}
+	return total
+}
+
+// stop 停止计时并丢弃未处理的变化
+func (q *changeQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.pending = make(map[string]map[string]bool)
+}
+
+// schedule 重新开始合并等待计时，调用时需持有锁
+func (q *changeQueue) schedule(ctx context.Context) {
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.timer = time.AfterFunc(watcherDebounce, func() { q.flush(ctx) })
+}
+
+// flush 对合并后的变化执行同步；已有同步在执行或已暂停时稍后重试
+func (q *changeQueue) flush(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	app := q.app
+	if app.syncPause.isPaused() {
+		q.mu.Lock()
+		q.schedule(ctx)
+		q.mu.Unlock()
+		return
+	}
+
+	runCtx, ok := app.beginSyncRun(ctx)
+	if !ok {
+		q.mu.Lock()
+		q.schedule(ctx)
+		q.mu.Unlock()
+		return
+	}
+	defer app.endSyncRun()
+
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = make(map[string]map[string]bool)
+	q.mu.Unlock()
+
+	startTime := time.Now()
+	status := SyncStatus{
+		Running:  true,
+		LastSync: startTime,
+		Errors:   []string{},
+		SyncMode: q.mode,
+	}
+
+	var ruleIDs []string
+	for id := range pending {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	for _, id := range ruleIDs {
+		// 使用最新的规则，已删除或禁用的规则不再同步
+		rule, err := app.GetSyncRuleByID(id)
+		if err != nil || !rule.Enabled {
+			continue
+		}
+		var paths []string
+		for path := range pending[id] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		if err := q.apply(runCtx, rule, paths, &status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("同步规则 '%s' 失败: %v", rule.Name, err))
+		}
+	}
+
+	status.Running = false
+	if status.FilesUploaded == 0 && status.FilesDownloaded == 0 && len(status.Errors) == 0 {
+		return
+	}
+	app.recordSyncHistory(status, time.Since(startTime))
+	fmt.Printf("变化同步完成: 上传 %d 个文件, 下载 %d 个文件, %d 个错误\n",
+		status.FilesUploaded, status.FilesDownloaded, len(status.Errors))
+}
+
+// syncWatcher 监控同步规则的本地目录，合并变化后触发针对变化路径的同步
+type syncWatcher struct {
+	app      *App
+	watcher  *fsnotify.Watcher
+	queue    *changeQueue
+	rules    map[string]SyncRule // 规则ID -> 规则
+	scanOnly map[string]bool     // 无法完整监控的规则
+	dirs     int
+	mu       sync.Mutex
+}
+
+// isWatchLimitError 判断是否为系统监控数量或文件句柄达到上限
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// startFileWatcher 为所有启用的上传和双向同步规则启动文件监控
+func (a *App) startFileWatcher(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监控失败: %v", err)
+	}
+
+	w := &syncWatcher{
+		app:      a,
+		watcher:  watcher,
+		queue:    newChangeQueue(a, "watch", a.syncChangedPaths),
+		rules:    make(map[string]SyncRule),
+		scanOnly: make(map[string]bool),
+	}
+
+	for _, rule := range a.GetSyncRules() {
+		// 只有本地变化需要上传的规则才需要监控
+		if !rule.Enabled || rule.Direction == "download" {
+			continue
+		}
+		w.rules[rule.ID] = rule
+		if err := w.addTree(rule.LocalPath); err != nil {
+			if !isWatchLimitError(err) {
+				watcher.Close()
+				return err
+			}
+			// 达到系统监控上限，该规则改为依靠定时扫描
+			fmt.Printf("规则 '%s' 的目录数量超过系统监控上限，改为定时扫描: %v\n", rule.Name, err)
+			w.scanOnly[rule.ID] = true
+		}
+	}
+
+	a.syncMu.Lock()
+	a.watcher = w
+	a.syncMu.Unlock()
+
+	go w.run(ctx)
+	fmt.Printf("文件监控已启动，共监控 %d 个目录\n", w.dirs)
+	return nil
+}
+
+// stopFileWatcher 停止文件监控
+func (a *App) stopFileWatcher() {
+	a.syncMu.Lock()
+	w := a.watcher
+	a.watcher = nil
+	a.syncMu.Unlock()
+
+	if w != nil {
+		w.close()
+	}
+}
+
+// restartFileWatcher 同步规则变化后重新建立文件监控
+func (a *App) restartFileWatcher() {
+	a.syncMu.Lock()
+	running := a.watcher != nil
+	ctx := a.serviceCtx
+	a.syncMu.Unlock()
+
+	if !running || ctx == nil {
+		return
+	}
+
+	a.stopFileWatcher()
+	if err := a.startFileWatcher(ctx); err != nil {
+		fmt.Printf("重新启动文件监控失败: %v\n", err)
+	}
+}
+
+// GetFileWatcherStatus 获取文件监控状态
+func (a *App) GetFileWatcherStatus() FileWatcherStatus {
+	status := FileWatcherStatus{
+		Enabled:           !a.disableFileWatcher,
+		WatchedRules:      []string{},
+		ScanOnlyRules:     []string{},
+		RemoteNotifyRules: []string{},
+		RemotePollRules:   []string{},
+	}
+
+	a.syncMu.Lock()
+	w := a.watcher
+	r := a.remoteWatcher
+	a.syncMu.Unlock()
+	if r != nil {
+		r.status(&status)
+	}
+	if w == nil {
+		return status
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status.Running = true
+	status.WatchedDirs = w.dirs
+	for id, rule := range w.rules {
+		if w.scanOnly[id] {
+			status.ScanOnlyRules = append(status.ScanOnlyRules, rule.Name)
+		} else {
+			status.WatchedRules = append(status.WatchedRules, rule.Name)
+		}
+	}
+	status.PendingFiles += w.queue.size()
+	sort.Strings(status.WatchedRules)
+	sort.Strings(status.ScanOnlyRules)
+	return status
+}
+
+// SetFileWatcherEnabled 启用或禁用文件监控，同步服务运行时立即生效
+func (a *App) SetFileWatcherEnabled(enabled bool) error {
+	a.disableFileWatcher = !enabled
+
+	a.syncMu.Lock()
+	ctx := a.serviceCtx
+	a.syncMu.Unlock()
+
+	a.stopFileWatcher()
+	a.stopRemoteWatcher()
+	if enabled && ctx != nil {
+		a.startRemoteWatcher(ctx)
+		if err := a.startFileWatcher(ctx); err != nil {
+			return err
+		}
+	}
+
+	return a.saveConfig()
+}
+
+// addTree 递归监控目录及其所有子目录
+func (w *syncWatcher) addTree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 目录在遍历过程中被删除时忽略
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		w.dirs++
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// close 停止监控并丢弃未处理的变化
+func (w *syncWatcher) close() {
+	w.queue.stop()
+	w.watcher.Close()
+}
+
+// run 处理文件系统事件，直到同步服务停止
+func (w *syncWatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			w.close()
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(ctx, event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("文件监控错误: %v\n", err)
+		}
+	}
+}
+
+// handleEvent 记录变化的路径，新建的目录加入监控
+func (w *syncWatcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod || isTempDownloadFile(event.Name) {
+		return
+	}
+
+	rule, ok := w.ruleFor(event.Name)
+	if !ok {
+		return
+	}
+
+	// 新建或移入的目录：加入监控，并把其中已有的文件作为变化
+	if event.Op.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.addTree(event.Name); err != nil && isWatchLimitError(err) {
+				w.mu.Lock()
+				w.scanOnly[rule.ID] = true
+				w.mu.Unlock()
+				fmt.Printf("规则 '%s' 的目录数量超过系统监控上限，改为定时扫描\n", rule.Name)
+			}
+			files, err := getAllFiles(event.Name)
+			if err == nil {
+				for _, file := range files {
+					w.enqueue(ctx, rule, file)
+				}
+			}
+			return
+		}
+	}
+
+	w.enqueue(ctx, rule, event.Name)
+}
+
+// ruleFor 查找路径所属的同步规则
+func (w *syncWatcher) ruleFor(path string) (SyncRule, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, rule := range w.rules {
+		root := filepath.Clean(rule.LocalPath)
+		if path == root || strings.HasPrefix(path, root+string(os.PathSeparator)) {
+			return rule, true
+		}
+	}
+	return SyncRule{}, false
+}
+
+// enqueue 加入待同步的变化路径
+func (w *syncWatcher) enqueue(ctx context.Context, rule SyncRule, path string) {
+	relPath, err := syncRelPath(rule.LocalPath, path)
+	if err != nil || relPath == "." {
+		return
+	}
+	w.queue.add(ctx, rule.ID, relPath)
+}
+
+// syncChangedPaths 只同步发生变化的路径
+// 路径消失时可能是删除或重命名，交给完整的重命名识别和删除传播处理，以保留删除保护
+func (a *App) syncChangedPaths(ctx context.Context, rule SyncRule, relPaths []string, status *SyncStatus) error {
+	// 监控启动后切换到备份模式时，变化留给下一次定时备份
+	if a.syncMode == "backup" {
+		return nil
+	}
+
+	config := syncConfigFromRule(rule)
+	filtered := a.syncMode == "selective" || a.syncMode == "incremental"
+
+	var existing []string
+	removed := false
+	for _, relPath := range relPaths {
+		localPath := filepath.Join(config.LocalPath, filepath.FromSlash(relPath))
+		info, err := os.Stat(localPath)
+		if os.IsNotExist(err) {
+			removed = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("获取本地文件信息失败: %v", err)
+		}
+		if info.IsDir() || (filtered && matchesFilter(localPath, rule.Filters)) {
+			continue
+		}
+		existing = append(existing, localPath)
+	}
+
+	if removed {
+		if err := a.applyRenames(ctx, config, status); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("检测重命名失败: %v", err))
+		}
+		if rule.Direction == "bidirectional" {
+			if err := a.propagateDeletions(ctx, config, status); err != nil {
+				return err
+			}
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	// 只查询变化文件对应的远程对象，不列出整个远程目录
+	remoteFileMap := make(map[string]MinioFileInfo)
+	for _, localPath := range existing {
+		relPath, err := syncRelPath(config.LocalPath, localPath)
+		if err != nil {
+			return fmt.Errorf("计算相对路径失败: %v", err)
+		}
+		remotePath := a.syncRemoteKey(config.RemotePath, relPath)
+		info, err := a.GetMinioFileInfo(remotePath)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				continue
+			}
+			return fmt.Errorf("获取远程文件信息失败: %v", err)
+		}
+		remoteFileMap[remotePath] = info
+	}
+
+	// 加载同步索引
+	index, err := a.loadSyncIndex(config)
+	if err != nil {
+		return err
+	}
+	defer a.saveSyncIndexQuietly(index)
+
+	var tasks []transferTask
+	for _, localPath := range existing {
+		task, err := a.planUpload(index, config, localPath, remoteFileMap)
+		if err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("上传文件失败: %v", err))
+			continue
+		}
+		if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+
+	result := a.runTransfers(ctx, config, index, tasks)
+	status.FilesUploaded += result.Uploaded
+	status.Errors = append(status.Errors, result.Errors...)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// progressEmitInterval 传输进度事件的最小发送间隔，避免频繁刷新前端
+const progressEmitInterval = 200 * time.Millisecond
+
+// transferProgress 单个文件传输的字节级进度
+type transferProgress struct {
+	app      *App
+	file     string
+	total    int64
+	done     int64
+	pending  int64 // 尚未计入同步进度的字节数
+	lastEmit time.Time
+	mu       sync.Mutex
+}
+
+// newTransferProgress 创建文件传输进度，total 为文件总字节数
+func (a *App) newTransferProgress(file string, total int64) *transferProgress {
+	return &transferProgress{
+		app:   a,
+		file:  file,
+		total: total,
+	}
+}
+
+// add 记录新传输的字节数，并按间隔更新同步进度
+func (p *transferProgress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	p.pending += n
+	if p.done < p.total && time.Since(p.lastEmit) < progressEmitInterval {
+		return
+	}
+
+	p.app.updateTransferProgress(p.file, p.done, p.total, p.pending)
+	p.pending = 0
+	p.lastEmit = time.Now()
+}
+
+// Read 实现 io.Reader，供 minio.PutObjectOptions.Progress 使用，每次读取代表已上传的字节数
+func (p *transferProgress) Read(b []byte) (int, error) {
+	p.add(int64(len(b)))
+	return len(b), nil
+}
+
+// wrap 包装读取器，读取数据时同步记录进度
+func (p *transferProgress) wrap(r io.Reader) io.Reader {
+	return &progressReader{reader: r, progress: p}
+}
+
+// progressReader 读取时记录传输进度的读取器
+type progressReader struct {
+	reader   io.Reader
+	progress *transferProgress
+}
+
+// Read 实现 io.Reader
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.progress.add(int64(n))
+	}
+	return n, err
+}
+
+// updateTransferProgress 将当前文件的传输进度写入同步进度并通知前端
+func (a *App) updateTransferProgress(file string, done, total, delta int64) {
+	syncProgressMu.Lock()
+	syncProgress.CurrentFile = file
+	syncProgress.CurrentFileBytes = done
+	syncProgress.CurrentFileSize = total
+	syncProgress.TransferredBytes += delta
+	progress := syncProgress
+	syncProgressMu.Unlock()
+
+	// 命令行模式下没有前端上下文，只更新进度数据
+	if a.ctx == nil {
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, "sync-progress-update", progress)
+}